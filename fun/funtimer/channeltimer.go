@@ -1,6 +1,7 @@
 package funtimer
 
 import (
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
 	"sync"
 	"time"
 )
@@ -14,18 +15,22 @@ type ChannelTimer struct {
 	resetCh chan time.Duration
 	stopped bool
 	mu      sync.Mutex
+	clock   clock.Clock
 }
 
 // NewTimer creates a new ChannelTimer that will send
 // the current time on its channel after at least duration d.
 // @idiomatic: pointer return
-func NewTimer(d time.Duration) *ChannelTimer {
+func NewTimer(d time.Duration, opts ...clock.Option) *ChannelTimer {
+	clk := clock.FromOptions(opts)
+
 	t := ChannelTimer{
 		C:       make(chan time.Time, 1),
 		stopCh:  make(chan struct{}),
 		resetCh: make(chan time.Duration, 1),
 		stopped: false,
 		mu:      sync.Mutex{},
+		clock:   clk,
 	}
 
 	// @idiomatic: запуск снаружи в goroutine, вместо того чтобы запускать внутри нее
@@ -38,12 +43,12 @@ func NewTimer(d time.Duration) *ChannelTimer {
 
 func (t *ChannelTimer) run(d time.Duration) {
 	// deadline удобнее, чем elapsed
-	deadline := time.Now().Add(d)
+	deadline := t.clock.Now().Add(d)
 	step := 10 * time.Millisecond
 
 	for {
 		// Дождались
-		now := time.Now()
+		now := t.clock.Now()
 		if now.After(deadline) || now.Equal(deadline) {
 			// Выход в обоих случаях.
 			// return внутри каждой ветки, — он более читаемый и надёжный для сопровождения.
@@ -65,7 +70,7 @@ func (t *ChannelTimer) run(d time.Duration) {
 			return
 		default:
 			// ничего не произошло, спим
-			time.Sleep(step)
+			t.clock.Sleep(step)
 		}
 	}
 }
@@ -133,16 +138,16 @@ func (t *ChannelTimer) Reset(d time.Duration) bool {
 // After waits for the duration to elapse and then sends the current time
 // on the returned channel.
 // It is equivalent to [NewTimer](d).C.
-func After(d time.Duration) <-chan time.Time {
-	return NewTimer(d).C
+func After(d time.Duration, opts ...clock.Option) <-chan time.Time {
+	return NewTimer(d, opts...).C
 }
 
 // AfterFunc waits for the duration to elapse and then calls f
 // in its own goroutine. It returns a [Timer] that can
 // be used to cancel the call using its Stop method.
 // The returned Timer's C field is not used and will be nil.
-func AfterFunc(d time.Duration, f func()) *ChannelTimer {
-	t := NewTimer(d)
+func AfterFunc(d time.Duration, f func(), opts ...clock.Option) *ChannelTimer {
+	t := NewTimer(d, opts...)
 
 	go func() {
 		// resetCh - здесь не надо прослушивать, потому что при немЮ, даже если время истекло f может не выполнится
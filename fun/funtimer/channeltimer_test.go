@@ -1,6 +1,7 @@
 package funtimer
 
 import (
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
 	"testing"
 	"time"
 )
@@ -66,4 +67,27 @@ func TestChannelTimer(t *testing.T) {
 			t.Fatal("Reset returned true after f executed")
 		}
 	})
+
+	t.Run("fake_clock", func(t *testing.T) {
+		c := clock.NewFake()
+		tm := NewTimer(50*time.Millisecond, clock.With(c))
+
+		c.BlockUntil(1)
+		c.Advance(40 * time.Millisecond)
+
+		select {
+		case <-tm.C:
+			t.Fatalf("timer fired too early")
+		default:
+		}
+
+		c.Advance(10 * time.Millisecond)
+
+		select {
+		case <-tm.C:
+			t.Log("expected")
+		case <-time.After(time.Second):
+			t.Fatalf("timer did not fire")
+		}
+	})
 }
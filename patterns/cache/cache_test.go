@@ -2,6 +2,7 @@ package cache
 
 import (
 	"fmt"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
 	"sync"
 	"testing"
 	"time"
@@ -38,6 +39,37 @@ func TestSingleCache(t *testing.T) {
 		c := NewSingleCache[string, string]()
 		janitor(t, c)
 	})
+
+	t.Run("fake_clock_ttl", func(t *testing.T) {
+		fc := clock.NewFake()
+		c := NewSingleCache[string, string](clock.With(fc))
+		fakeClockTTL(t, fc, c)
+	})
+
+	t.Run("fake_clock_janitor", func(t *testing.T) {
+		fc := clock.NewFake()
+		c := NewSingleCache[string, string](clock.With(fc))
+
+		c.Set("key", "val", 100*time.Millisecond)
+		c.UseJanitor(t.Context(), 50*time.Millisecond)
+		fc.BlockUntil(1)
+
+		fc.Advance(150 * time.Millisecond)
+
+		// janitor работает в своей goroutine, ждем пока он дочистит
+		sc := c.(*singleCache[string, string])
+		for range 100 {
+			sc.mu.RLock()
+			n := len(sc.mp)
+			sc.mu.RUnlock()
+
+			if n == 0 {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatalf("expected janitor to remove expired key")
+	})
 }
 
 func TestShardedCache(t *testing.T) {
@@ -71,6 +103,12 @@ func TestShardedCache(t *testing.T) {
 		c := NewShardedCache[string, string](8)
 		janitor(t, c)
 	})
+
+	t.Run("fake_clock_ttl", func(t *testing.T) {
+		fc := clock.NewFake()
+		c := NewShardedCache[string, string](8, clock.With(fc))
+		fakeClockTTL(t, fc, c)
+	})
 }
 
 func sequentially(t *testing.T, cache Cache[string, string]) {
@@ -109,6 +147,25 @@ func ttl(t *testing.T, cache Cache[string, string]) {
 	}
 }
 
+// fakeClockTTL - то же, что ttl, но без sleep.
+func fakeClockTTL(t *testing.T, fc *clock.Fake, cache Cache[string, string]) {
+	cache.Set("key", "val", 10*time.Millisecond)
+
+	fc.Advance(10 * time.Millisecond)
+
+	_, ok := cache.Get("key")
+	if !ok {
+		t.Fatalf("expected key %q to be found", "key")
+	}
+
+	fc.Advance(time.Nanosecond)
+
+	_, ok = cache.Get("key")
+	if ok {
+		t.Fatalf("expected key %q to be missing", "key")
+	}
+}
+
 func janitor(t *testing.T, cache Cache[string, string]) {
 	cache.Set("key", "val", 100*time.Millisecond)
 
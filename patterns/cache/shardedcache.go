@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
 	"hash/maphash"
 	"time"
 )
@@ -15,11 +16,11 @@ type shardedCache[K comparable, V any] struct {
 	shardCount int
 }
 
-func NewShardedCache[K comparable, V any](shardCount int) Cache[K, V] {
+func NewShardedCache[K comparable, V any](shardCount int, opts ...clock.Option) Cache[K, V] {
 	// @idiomatic: pre-initialized shards (вместо lazy resolve + mutex там и двойная проверка)
 	sl := make([]*singleCache[K, V], shardCount)
 	for i := range shardCount {
-		sl[i] = NewSingleCache[K, V]().(*singleCache[K, V])
+		sl[i] = NewSingleCache[K, V](opts...).(*singleCache[K, V])
 	}
 
 	return &shardedCache[K, V]{
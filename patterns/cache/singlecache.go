@@ -2,14 +2,16 @@ package cache
 
 import (
 	"context"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
 	"sync"
 	"time"
 )
 
 // singleCache простой кеш без шардирования.
 type singleCache[K comparable, V any] struct {
-	mp map[K]cacheItem[V]
-	mu sync.RWMutex
+	mp    map[K]cacheItem[V]
+	mu    sync.RWMutex
+	clock clock.Clock
 }
 
 func (c *singleCache[K, V]) Get(key K) (V, bool) {
@@ -39,21 +41,22 @@ func (c *singleCache[K, V]) Set(key K, value V, ttl time.Duration) {
 	var exp time.Time
 
 	if ttl > 0 {
-		exp = time.Now().Add(ttl)
+		exp = c.clock.Now().Add(ttl)
 	}
 
 	c.mp[key] = cacheItem[V]{value, exp}
 }
 
-func NewSingleCache[K comparable, V any]() Cache[K, V] {
+func NewSingleCache[K comparable, V any](opts ...clock.Option) Cache[K, V] {
 	return &singleCache[K, V]{
-		mp: make(map[K]cacheItem[V]),
+		mp:    make(map[K]cacheItem[V]),
+		clock: clock.FromOptions(opts),
 	}
 }
 
 func (c *singleCache[K, V]) UseJanitor(ctx context.Context, tick time.Duration) {
 	go func() {
-		timer := time.NewTicker(tick)
+		timer := c.clock.NewTicker(tick)
 		defer timer.Stop()
 
 		for {
@@ -61,7 +64,7 @@ func (c *singleCache[K, V]) UseJanitor(ctx context.Context, tick time.Duration)
 			case <-ctx.Done():
 				timer.Stop()
 				return
-			case <-timer.C:
+			case <-timer.C():
 				c.mu.Lock()
 				for key, item := range c.mp {
 					if c.isExpired(&item) {
@@ -76,5 +79,5 @@ func (c *singleCache[K, V]) UseJanitor(ctx context.Context, tick time.Duration)
 
 // @idiomatic: pass by reference to prevent copying
 func (c *singleCache[K, V]) isExpired(item *cacheItem[V]) bool {
-	return !item.expire.IsZero() && item.expire.Before(time.Now())
+	return !item.expire.IsZero() && item.expire.Before(c.clock.Now())
 }
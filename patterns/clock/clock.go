@@ -0,0 +1,128 @@
+package clock
+
+import (
+	"time"
+)
+
+// Clock - источник времени, который можно подменить в тестах.
+//
+// Для чего:
+// Код, который напрямую вызывает time.Now/time.After, тестируется только через sleep: тесты долгие и flaky.
+// Если время внедряется (dependency injection), то в тестах используется Fake и время двигается явно через Advance.
+//
+// @idiomatic: accept interfaces - конструкторы принимают Clock, по умолчанию Real
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+	AfterFunc(d time.Duration, f func()) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer - аналог *time.Timer. Канал отдается методом, потому что у интерфейса не может быть полей.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker - аналог *time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration)
+}
+
+// Real - Clock поверх пакета time.
+var Real Clock = realClock{}
+
+// OrReal возвращает c, а если он nil - Real. Удобно для zero-value конфигураций.
+func OrReal(c Clock) Clock {
+	if c == nil {
+		return Real
+	}
+	return c
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+func (realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTimer struct {
+	t *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.t.Stop()
+}
+
+func (t realTimer) Reset(d time.Duration) bool {
+	return t.t.Reset(d)
+}
+
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t realTicker) Stop() {
+	t.t.Stop()
+}
+
+func (t realTicker) Reset(d time.Duration) {
+	t.t.Reset(d)
+}
+
+// Option - необязательный параметр конструкторов, которые принимают ...clock.Option.
+type Option func(*Clock)
+
+// With подменяет источник времени (в тестах - Fake).
+func With(c Clock) Option {
+	return func(dst *Clock) {
+		*dst = OrReal(c)
+	}
+}
+
+// FromOptions возвращает Clock из opts, по умолчанию Real.
+func FromOptions(opts []Option) Clock {
+	c := Real
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
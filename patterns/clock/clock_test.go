@@ -0,0 +1,143 @@
+package clock
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	t.Run("now_moves_only_on_advance", func(t *testing.T) {
+		c := NewFake()
+		start := c.Now()
+
+		time.Sleep(5 * time.Millisecond)
+		if c.Since(start) != 0 {
+			t.Fatalf("time moved without advance")
+		}
+
+		c.Advance(time.Hour)
+		if got := c.Since(start); got != time.Hour {
+			t.Fatalf("got %v, want %v", got, time.Hour)
+		}
+	})
+
+	t.Run("timer_fires_on_deadline", func(t *testing.T) {
+		c := NewFake()
+		timer := c.NewTimer(time.Second)
+
+		c.Advance(999 * time.Millisecond)
+		select {
+		case <-timer.C():
+			t.Fatalf("fired too early")
+		default:
+		}
+
+		c.Advance(time.Millisecond)
+		select {
+		case <-timer.C():
+		default:
+			t.Fatalf("did not fire")
+		}
+	})
+
+	t.Run("stop_and_reset", func(t *testing.T) {
+		c := NewFake()
+		timer := c.NewTimer(time.Second)
+
+		if !timer.Stop() {
+			t.Fatalf("Stop returned false for active timer")
+		}
+
+		c.Advance(time.Second)
+		select {
+		case <-timer.C():
+			t.Fatalf("fired after stop")
+		default:
+		}
+
+		if timer.Reset(time.Second) {
+			t.Fatalf("Reset returned true for stopped timer")
+		}
+
+		c.Advance(time.Second)
+		select {
+		case <-timer.C():
+		default:
+			t.Fatalf("did not fire after reset")
+		}
+	})
+
+	t.Run("after_func_in_order", func(t *testing.T) {
+		c := NewFake()
+
+		var fired []int
+		var at []time.Duration
+		start := c.Now()
+
+		for _, i := range []int{3, 1, 2} {
+			c.AfterFunc(time.Duration(i)*time.Second, func() {
+				fired = append(fired, i)
+				at = append(at, c.Since(start))
+			})
+		}
+
+		c.Advance(10 * time.Second)
+
+		if !slices.Equal(fired, []int{1, 2, 3}) {
+			t.Errorf("got %v, want ordered", fired)
+		}
+		if !slices.Equal(at, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}) {
+			t.Errorf("got %v, want at deadlines", at)
+		}
+	})
+
+	t.Run("ticker", func(t *testing.T) {
+		c := NewFake()
+		ticker := c.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for i := 0; i < 3; i++ {
+			c.Advance(time.Second)
+			select {
+			case <-ticker.C():
+			default:
+				t.Fatalf("tick %d missed", i)
+			}
+		}
+	})
+
+	t.Run("sleep_and_block_until", func(t *testing.T) {
+		c := NewFake()
+		done := make(chan struct{})
+
+		go func() {
+			c.Sleep(time.Minute)
+			close(done)
+		}()
+
+		c.BlockUntil(1)
+		c.Advance(time.Minute)
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("sleep was not woken")
+		}
+	})
+}
+
+func TestFromOptions(t *testing.T) {
+	if got := FromOptions(nil); got != Real {
+		t.Errorf("got %v, want Real", got)
+	}
+
+	fake := NewFake()
+	if got := FromOptions([]Option{With(fake)}); got != fake {
+		t.Errorf("got %v, want fake", got)
+	}
+
+	if got := FromOptions([]Option{With(nil)}); got != Real {
+		t.Errorf("got %v, want Real for nil", got)
+	}
+}
@@ -0,0 +1,225 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake - управляемые часы для тестов.
+//
+// Время стоит на месте, пока его не сдвинут через Advance. При сдвиге таймеры и тикеры срабатывают
+// строго по порядку своих дедлайнов, а Now внутри сработавшего таймера равно его дедлайну.
+// Функции AfterFunc вызываются синхронно внутри Advance, поэтому после возврата из Advance их эффект уже виден.
+//
+// Типичная проблема таких тестов: goroutine еще не успела создать таймер, а тест уже сдвинул время.
+// Для этого есть BlockUntil - ждем, пока нужное количество таймеров будет ожидать срабатывания.
+type Fake struct {
+	now    time.Time
+	timers []*fakeTimer
+	mu     sync.Mutex
+	cond   *sync.Cond // сигнал об изменении количества таймеров для BlockUntil
+}
+
+// NewFake создает часы, стоящие на фиксированной (ненулевой) дате.
+// Нулевое время не подходит: его часто используют как "никогда не было".
+func NewFake() *Fake {
+	return NewFakeAt(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+// NewFakeAt создает часы, стоящие на указанной дате.
+func NewFakeAt(now time.Time) *Fake {
+	c := &Fake{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *Fake) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *Fake) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// Sleep блокируется, пока время не сдвинут на d.
+func (c *Fake) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+func (c *Fake) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+func (c *Fake) NewTimer(d time.Duration) Timer {
+	return c.add(d, 0, nil)
+}
+
+func (c *Fake) AfterFunc(d time.Duration, f func()) Timer {
+	return c.add(d, 0, f)
+}
+
+func (c *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	return fakeTicker{c.add(d, d, nil)}
+}
+
+// Advance сдвигает время на d и по порядку срабатывает все таймеры, чей дедлайн наступил.
+func (c *Fake) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	target := c.now.Add(d)
+
+	for {
+		t := c.earliest()
+		if t == nil || t.when.After(target) {
+			break
+		}
+
+		if t.when.After(c.now) {
+			c.now = t.when
+		}
+
+		if t.period > 0 {
+			// тикер: следующий тик, пропущенные тики не копятся (буфер 1, как у time.Ticker)
+			t.when = t.when.Add(t.period)
+		} else {
+			c.remove(t)
+		}
+
+		if t.f != nil {
+			// Вызываем без mutex, потому что f может сама обращаться к часам.
+			c.mu.Unlock()
+			t.f()
+			c.mu.Lock()
+			continue
+		}
+
+		select {
+		case t.ch <- c.now:
+		default:
+		}
+	}
+
+	c.now = target
+}
+
+// BlockUntil ждет, пока активных таймеров и тикеров станет не меньше n.
+func (c *Fake) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+// Waiters возвращает количество активных таймеров и тикеров.
+func (c *Fake) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.timers)
+}
+
+func (c *Fake) add(d time.Duration, period time.Duration, f func()) *fakeTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{
+		clock:  c,
+		ch:     make(chan time.Time, 1),
+		when:   c.now.Add(d),
+		period: period,
+		f:      f,
+	}
+	c.timers = append(c.timers, t)
+	c.cond.Broadcast()
+
+	return t
+}
+
+// earliest - таймер с ближайшим дедлайном, при равенстве - созданный раньше.
+func (c *Fake) earliest() *fakeTimer {
+	var res *fakeTimer
+	for _, t := range c.timers {
+		if res == nil || t.when.Before(res.when) {
+			res = t
+		}
+	}
+	return res
+}
+
+func (c *Fake) remove(t *fakeTimer) bool {
+	for i, v := range c.timers {
+		if v == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			c.cond.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock  *Fake
+	ch     chan time.Time
+	when   time.Time
+	period time.Duration // > 0 - тикер
+	f      func()
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+// Stop как и у time.Timer (начиная с go 1.23) гарантирует, что после него старое значение из канала не прочитать.
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	t.drain()
+	return t.clock.remove(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	t.drain()
+	active := t.clock.remove(t)
+
+	t.when = t.clock.now.Add(d)
+	if t.period > 0 {
+		t.period = d
+	}
+	t.clock.timers = append(t.clock.timers, t)
+	t.clock.cond.Broadcast()
+
+	return active
+}
+
+// fakeTicker - тот же fakeTimer, но с сигнатурами методов Ticker.
+type fakeTicker struct {
+	*fakeTimer
+}
+
+func (t fakeTicker) Stop() {
+	t.fakeTimer.Stop()
+}
+
+func (t fakeTicker) Reset(d time.Duration) {
+	t.fakeTimer.Reset(d)
+}
+
+func (t *fakeTimer) drain() {
+	select {
+	case <-t.ch:
+	default:
+	}
+}
@@ -0,0 +1,422 @@
+package commitlog
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
+	"hash/fnv"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrOffsetOutOfRange  = errors.New("commitlog: offset is out of range")
+	ErrInvalidPartition  = errors.New("commitlog: invalid partition")
+	ErrPartitionMismatch = errors.New("commitlog: partition count does not match existing log")
+	ErrClosed            = errors.New("commitlog: log is closed")
+)
+
+// Log - локальный партиционированный append-only лог на диске, как топик Kafka (см. using/kafka), но in-process.
+// Нужен там, где сообщения должны пережить перезапуск или их нужно перечитывать (в отличие от pubsub.Broker).
+//
+// Требования:
+//   - запись только в конец партиции, каждая запись получает offset - номер по порядку внутри партиции
+//   - записи с одинаковым ключом попадают в одну партицию, поэтому их порядок сохраняется. Без ключа - round-robin
+//   - читать можно с любого offset, чтение ничего не удаляет
+//   - consumer group сохраняет, до какого offset дочитала (Commit), и продолжает с него после перезапуска
+//   - retention: старые сегменты удаляются целиком по размеру партиции и по возрасту
+//
+// Структура на диске:
+//
+//	dir/partitions/0/00000000000000000000.log, .index (см. segment.go)
+//	dir/groups/<group> - committed offsets группы
+//
+// Запись не делает fsync (как и Kafka по умолчанию - надежность за счет реплик): после падения процесса данные
+// сохранятся, после падения ОС - не обязательно. WithSyncEveryAppend или Sync меняют это.
+type Log struct {
+	dir        string
+	opts       options
+	partitions []*partition
+	nextRR     atomic.Uint64
+
+	groupsMu sync.Mutex
+	closed   atomic.Bool
+}
+
+// Record - запись лога. Offset, Partition и Time заполняет Append.
+type Record struct {
+	Partition int
+	Offset    int64
+	Time      time.Time
+	Key       []byte
+	Value     []byte
+}
+
+type partition struct {
+	mu       sync.RWMutex
+	dir      string
+	segments []*segment // по возрастанию base, последний - активный
+}
+
+// Open открывает лог в dir или создает новый. Количество партиций у существующего лога менять нельзя.
+func Open(dir string, opts ...OptionFunc) (*Log, error) {
+	o := options{
+		partitions:   1,
+		segmentBytes: 64 << 20,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.clock = clock.OrReal(o.clock)
+	if o.partitions <= 0 {
+		panic("partitions must be greater than 0")
+	}
+
+	existing, err := os.ReadDir(filepath.Join(dir, "partitions"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if len(existing) > 0 && len(existing) != o.partitions {
+		return nil, fmt.Errorf("%w: %d existing, %d requested", ErrPartitionMismatch, len(existing), o.partitions)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "groups"), 0o755); err != nil {
+		return nil, err
+	}
+
+	l := &Log{dir: dir, opts: o}
+	for i := range o.partitions {
+		p, err := openPartition(filepath.Join(dir, "partitions", strconv.Itoa(i)))
+		if err != nil {
+			_ = l.Close()
+			return nil, err
+		}
+		l.partitions = append(l.partitions, p)
+	}
+
+	return l, nil
+}
+
+func openPartition(dir string) (*partition, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var bases []int64
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), logSuffix)
+		if !ok {
+			continue
+		}
+		base, err := strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected file %s: %w", e.Name(), err)
+		}
+		bases = append(bases, base)
+	}
+	slices.Sort(bases)
+
+	if len(bases) == 0 {
+		bases = []int64{0}
+	}
+
+	p := &partition{dir: dir}
+	for i, base := range bases {
+		s, err := openSegment(dir, base, i == len(bases)-1)
+		if err != nil {
+			p.close()
+			return nil, err
+		}
+		p.segments = append(p.segments, s)
+	}
+
+	return p, nil
+}
+
+// Partitions - количество партиций.
+func (l *Log) Partitions() int {
+	return len(l.partitions)
+}
+
+// PartitionFor - партиция для ключа: одинаковые ключи всегда в одной партиции.
+// Для nil ключа - следующая по round-robin.
+func (l *Log) PartitionFor(key []byte) int {
+	if key == nil {
+		return int(l.nextRR.Add(1)-1) % len(l.partitions)
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write(key)
+	return int(h.Sum32() % uint32(len(l.partitions)))
+}
+
+// Append дописывает запись в партицию по ключу и возвращает ее партицию и offset.
+func (l *Log) Append(key []byte, value []byte) (int, int64, error) {
+	if l.closed.Load() {
+		return 0, 0, ErrClosed
+	}
+
+	n := l.PartitionFor(key)
+	p := l.partitions[n]
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	active := p.active()
+	rec := Record{
+		Partition: n,
+		Offset:    active.next,
+		Time:      l.opts.clock.Now(),
+		Key:       key,
+		Value:     value,
+	}
+
+	// сегмент закрывается, когда следующая запись не влезает (но хотя бы одна запись в сегменте будет всегда)
+	if active.next > active.base && active.size+int64(headerSize+fixedSize+len(key)+len(value)) > l.opts.segmentBytes {
+		s, err := openSegment(p.dir, active.next, true)
+		if err != nil {
+			return 0, 0, err
+		}
+		p.segments = append(p.segments, s)
+		active = s
+
+		if err := l.retain(p); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if err := active.append(rec); err != nil {
+		return 0, 0, err
+	}
+	if l.opts.syncEveryAppend {
+		if err := active.sync(); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return n, rec.Offset, nil
+}
+
+// Read читает до max записей партиции начиная с offset. Если offset == Newest, то записей пока нет (пустой результат).
+// Offset меньше Oldest (удален retention) или больше Newest - ErrOffsetOutOfRange.
+func (l *Log) Read(partition int, offset int64, max int) ([]Record, error) {
+	p, err := l.partition(partition)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if offset < p.oldest() || offset > p.newest() {
+		return nil, fmt.Errorf("%w: %d not in [%d, %d]", ErrOffsetOutOfRange, offset, p.oldest(), p.newest())
+	}
+
+	// первый сегмент с base <= offset
+	i, found := slices.BinarySearchFunc(p.segments, offset, func(s *segment, offset int64) int {
+		return cmp.Compare(s.base, offset)
+	})
+	if !found {
+		i--
+	}
+
+	var res []Record
+	for ; i < len(p.segments) && len(res) < max; i++ {
+		s := p.segments[i]
+		for ; offset < s.next && len(res) < max; offset++ {
+			rec, _, err := s.read(offset)
+			if err != nil {
+				return res, err
+			}
+			rec.Partition = partition
+			res = append(res, rec)
+		}
+	}
+
+	return res, nil
+}
+
+// Oldest - первый доступный offset партиции (до него записи удалены retention).
+func (l *Log) Oldest(partition int) (int64, error) {
+	p, err := l.partition(partition)
+	if err != nil {
+		return 0, err
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.oldest(), nil
+}
+
+// Newest - offset, который получит следующая запись партиции.
+func (l *Log) Newest(partition int) (int64, error) {
+	p, err := l.partition(partition)
+	if err != nil {
+		return 0, err
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.newest(), nil
+}
+
+// Retain применяет retention ко всем партициям. Append делает это сам при смене сегмента,
+// но при редкой записи старые данные лучше удалять периодически.
+func (l *Log) Retain() error {
+	var errs []error
+	for _, p := range l.partitions {
+		p.mu.Lock()
+		errs = append(errs, l.retain(p))
+		p.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// retain удаляет старые сегменты (кроме активного), вызывается под p.mu.
+func (l *Log) retain(p *partition) error {
+	var total int64
+	for _, s := range p.segments {
+		total += s.size
+	}
+
+	now := l.opts.clock.Now()
+	for len(p.segments) > 1 {
+		oldest := p.segments[0]
+
+		expired := l.opts.retentionTime > 0 && now.Sub(oldest.maxTime) > l.opts.retentionTime
+		tooBig := l.opts.retentionBytes > 0 && total > l.opts.retentionBytes
+		if !expired && !tooBig {
+			break
+		}
+
+		if err := oldest.remove(); err != nil {
+			return err
+		}
+		total -= oldest.size
+		p.segments = p.segments[1:]
+	}
+
+	return nil
+}
+
+// Sync сбрасывает активные сегменты на диск.
+func (l *Log) Sync() error {
+	var errs []error
+	for _, p := range l.partitions {
+		p.mu.Lock()
+		errs = append(errs, p.active().sync())
+		p.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// Close закрывает файлы. После Close лог можно снова открыть через Open.
+func (l *Log) Close() error {
+	l.closed.Store(true)
+
+	var errs []error
+	for _, p := range l.partitions {
+		p.mu.Lock()
+		errs = append(errs, p.close())
+		p.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+func (l *Log) partition(n int) (*partition, error) {
+	if l.closed.Load() {
+		return nil, ErrClosed
+	}
+	if n < 0 || n >= len(l.partitions) {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidPartition, n)
+	}
+	return l.partitions[n], nil
+}
+
+func (p *partition) active() *segment {
+	return p.segments[len(p.segments)-1]
+}
+
+func (p *partition) oldest() int64 {
+	return p.segments[0].base
+}
+
+func (p *partition) newest() int64 {
+	return p.active().next
+}
+
+func (p *partition) close() error {
+	var errs []error
+	for _, s := range p.segments {
+		errs = append(errs, s.close())
+	}
+	return errors.Join(errs...)
+}
+
+type options struct {
+	partitions      int
+	segmentBytes    int64
+	retentionBytes  int64
+	retentionTime   time.Duration
+	syncEveryAppend bool
+	clock           clock.Clock
+}
+
+type OptionFunc func(*options)
+
+// WithPartitions - количество партиций, по умолчанию 1.
+func WithPartitions(val int) OptionFunc {
+	return func(o *options) {
+		o.partitions = val
+	}
+}
+
+// WithSegmentBytes - максимальный размер сегмента, по умолчанию 64MB (не больше 4GB: позиции в индексе uint32).
+func WithSegmentBytes(val int64) OptionFunc {
+	return func(o *options) {
+		o.segmentBytes = min(val, math.MaxUint32)
+	}
+}
+
+// WithRetentionBytes - максимальный размер партиции, 0 - без ограничения.
+// Удаляются целые сегменты, поэтому размер может превышать лимит на размер активного сегмента.
+func WithRetentionBytes(val int64) OptionFunc {
+	return func(o *options) {
+		o.retentionBytes = val
+	}
+}
+
+// WithRetentionTime - сегмент удаляется, когда его последней записи больше val, 0 - без ограничения.
+func WithRetentionTime(val time.Duration) OptionFunc {
+	return func(o *options) {
+		o.retentionTime = val
+	}
+}
+
+// WithSyncEveryAppend - fsync после каждой записи: медленно, но запись переживает падение ОС.
+func WithSyncEveryAppend(val bool) OptionFunc {
+	return func(o *options) {
+		o.syncEveryAppend = val
+	}
+}
+
+func WithClock(val clock.Clock) OptionFunc {
+	return func(o *options) {
+		o.clock = val
+	}
+}
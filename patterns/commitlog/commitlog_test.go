@@ -0,0 +1,237 @@
+package commitlog
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLog(t *testing.T) {
+	t.Run("same_key_same_partition", func(t *testing.T) {
+		l := mustOpen(t, t.TempDir(), WithPartitions(4))
+
+		first, _, _ := l.Append([]byte("user-1"), []byte("a"))
+		for i := range 10 {
+			p, offset, err := l.Append([]byte("user-1"), []byte("b"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p != first || offset != int64(i+1) {
+				t.Errorf("got partition %v offset %v, want %v and %v", p, offset, first, i+1)
+			}
+		}
+	})
+
+	t.Run("read_from_any_offset", func(t *testing.T) {
+		l := mustOpen(t, t.TempDir())
+		appendN(t, l, 5)
+
+		recs, err := l.Read(0, 2, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(recs) != 3 || recs[0].Offset != 2 || string(recs[0].Value) != "value-2" {
+			t.Errorf("got %+v, want 3 records from offset 2", recs)
+		}
+
+		if recs, _ := l.Read(0, 5, 10); len(recs) != 0 {
+			t.Errorf("got %v records at newest, want 0", len(recs))
+		}
+		if _, err := l.Read(0, 6, 10); !errors.Is(err, ErrOffsetOutOfRange) {
+			t.Errorf("got %v, want ErrOffsetOutOfRange", err)
+		}
+	})
+
+	t.Run("reopen", func(t *testing.T) {
+		dir := t.TempDir()
+
+		l := mustOpen(t, dir, WithSegmentBytes(100))
+		appendN(t, l, 10)
+		_ = l.Close()
+
+		l = mustOpen(t, dir, WithSegmentBytes(100))
+		if newest, _ := l.Newest(0); newest != 10 {
+			t.Errorf("got newest %v, want 10", newest)
+		}
+
+		recs, err := l.Read(0, 0, 100)
+		if err != nil || len(recs) != 10 || string(recs[9].Value) != "value-9" {
+			t.Errorf("got %v records (err %v), want 10", len(recs), err)
+		}
+
+		if _, offset, _ := l.Append(nil, []byte("next")); offset != 10 {
+			t.Errorf("got offset %v, want 10", offset)
+		}
+	})
+
+	t.Run("recovers_torn_write", func(t *testing.T) {
+		dir := t.TempDir()
+
+		l := mustOpen(t, dir)
+		appendN(t, l, 3)
+		_ = l.Close()
+
+		// последняя запись записана не полностью
+		path := segmentPath(filepath.Join(dir, "partitions", "0"), 0, logSuffix)
+		info, _ := os.Stat(path)
+		if err := os.Truncate(path, info.Size()-3); err != nil {
+			t.Fatal(err)
+		}
+
+		l = mustOpen(t, dir)
+		if newest, _ := l.Newest(0); newest != 2 {
+			t.Errorf("got newest %v, want 2", newest)
+		}
+
+		_, offset, _ := l.Append(nil, []byte("again"))
+		recs, _ := l.Read(0, offset, 1)
+		if offset != 2 || len(recs) != 1 || string(recs[0].Value) != "again" {
+			t.Errorf("got offset %v records %+v, want offset 2", offset, recs)
+		}
+	})
+
+	t.Run("retention_by_size", func(t *testing.T) {
+		dir := t.TempDir()
+		l := mustOpen(t, dir, WithSegmentBytes(100), WithRetentionBytes(250))
+
+		appendN(t, l, 50)
+
+		oldest, _ := l.Oldest(0)
+		if oldest == 0 {
+			t.Fatal("got oldest 0, want old segments removed")
+		}
+		if _, err := l.Read(0, 0, 1); !errors.Is(err, ErrOffsetOutOfRange) {
+			t.Errorf("got %v, want ErrOffsetOutOfRange", err)
+		}
+		if recs, err := l.Read(0, oldest, 100); err != nil || int64(len(recs)) != 50-oldest {
+			t.Errorf("got %v records (err %v), want %v", len(recs), err, 50-oldest)
+		}
+
+		files, _ := os.ReadDir(filepath.Join(dir, "partitions", "0"))
+		var size int64
+		for _, f := range files {
+			info, _ := f.Info()
+			if filepath.Ext(f.Name()) == logSuffix {
+				size += info.Size()
+			}
+		}
+		if size > 250+100 {
+			t.Errorf("got %v bytes on disk, want at most retention + segment", size)
+		}
+	})
+
+	t.Run("retention_by_time", func(t *testing.T) {
+		clk := clock.NewFake()
+		l := mustOpen(t, t.TempDir(), WithSegmentBytes(100), WithRetentionTime(time.Hour), WithClock(clk))
+
+		appendN(t, l, 5)
+		clk.Advance(2 * time.Hour)
+		appendN(t, l, 5)
+
+		if err := l.Retain(); err != nil {
+			t.Fatal(err)
+		}
+
+		// по 2 записи в сегменте: сегмент [4, 5] содержит и новую запись, поэтому остается
+		if oldest, _ := l.Oldest(0); oldest != 4 {
+			t.Errorf("got oldest %v, want 4", oldest)
+		}
+	})
+
+	t.Run("consumer_group", func(t *testing.T) {
+		dir := t.TempDir()
+
+		l := mustOpen(t, dir, WithPartitions(2))
+		if offset, _ := l.Committed("billing", 1); offset != 0 {
+			t.Errorf("got %v, want 0 before first commit", offset)
+		}
+
+		_ = l.Commit("billing", 1, 42)
+		_ = l.Commit("billing", 0, 7)
+		_ = l.Commit("audit", 1, 3)
+		_ = l.Close()
+
+		l = mustOpen(t, dir, WithPartitions(2))
+		for _, tt := range []struct {
+			group     string
+			partition int
+			want      int64
+		}{
+			{"billing", 0, 7},
+			{"billing", 1, 42},
+			{"audit", 1, 3},
+		} {
+			if got, err := l.Committed(tt.group, tt.partition); err != nil || got != tt.want {
+				t.Errorf("got %v (err %v) for %s/%d, want %v", got, err, tt.group, tt.partition, tt.want)
+			}
+		}
+
+		if err := l.Commit("../escape", 0, 1); !errors.Is(err, ErrInvalidGroup) {
+			t.Errorf("got %v, want ErrInvalidGroup", err)
+		}
+		if err := l.Commit(".hidden", 0, 1); !errors.Is(err, ErrInvalidGroup) {
+			t.Errorf("got %v, want ErrInvalidGroup", err)
+		}
+		if err := l.Commit("billing", 0, -1); !errors.Is(err, ErrOffsetOutOfRange) {
+			t.Errorf("got %v, want ErrOffsetOutOfRange", err)
+		}
+	})
+
+	t.Run("commit_keeps_similar_groups", func(t *testing.T) {
+		dir := t.TempDir()
+
+		// временный файл replaceFile не должен совпадать с файлом другой группы
+		l := mustOpen(t, dir)
+		_ = l.Commit("x.tmp", 0, 5)
+		_ = l.Commit("x", 0, 9)
+		_ = l.Close()
+
+		l = mustOpen(t, dir)
+		if got, err := l.Committed("x.tmp", 0); err != nil || got != 5 {
+			t.Errorf("got %v (err %v), want 5", got, err)
+		}
+		if got, err := l.Committed("x", 0); err != nil || got != 9 {
+			t.Errorf("got %v (err %v), want 9", got, err)
+		}
+	})
+
+	t.Run("partition_mismatch", func(t *testing.T) {
+		dir := t.TempDir()
+
+		l := mustOpen(t, dir, WithPartitions(2))
+		_ = l.Close()
+
+		if _, err := Open(dir, WithPartitions(3)); !errors.Is(err, ErrPartitionMismatch) {
+			t.Errorf("got %v, want ErrPartitionMismatch", err)
+		}
+	})
+}
+
+func mustOpen(t *testing.T, dir string, opts ...OptionFunc) *Log {
+	t.Helper()
+
+	l, err := Open(dir, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	return l
+}
+
+func appendN(t *testing.T, l *Log, n int) {
+	t.Helper()
+
+	start, _ := l.Newest(0)
+	for i := range n {
+		if _, _, err := l.Append(nil, []byte(fmt.Sprintf("value-%d", start+int64(i)))); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package commitlog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrInvalidGroup = errors.New("commitlog: invalid group name")
+
+// Consumer group
+//
+// Committed offset - следующий offset, который группа должна прочитать (как в Kafka): прочитали запись 41 -
+// коммитим 42. Лог не следит, кто читает, - группа сама коммитит после обработки. Если упасть между обработкой
+// и Commit, то после перезапуска записи будут прочитаны повторно (at-least-once).
+//
+// Файл группы перезаписывается целиком через временный файл и rename: rename атомарен,
+// поэтому после падения будет либо старая, либо новая версия, но не половина.
+// Но только с fsync: без него после падения ОС rename может оказаться на диске раньше данных
+// (пустой файл группы), или сам rename может потеряться.
+
+// Commit сохраняет offset группы для партиции.
+func (l *Log) Commit(group string, partition int, offset int64) error {
+	if offset < 0 {
+		return fmt.Errorf("%w: negative offset %d", ErrOffsetOutOfRange, offset)
+	}
+	if _, err := l.partition(partition); err != nil {
+		return err
+	}
+	path, err := l.groupPath(group)
+	if err != nil {
+		return err
+	}
+
+	l.groupsMu.Lock()
+	defer l.groupsMu.Unlock()
+
+	offsets, err := readOffsets(path)
+	if err != nil {
+		return err
+	}
+	offsets[partition] = offset
+
+	var b strings.Builder
+	for p := range len(l.partitions) {
+		if offset, ok := offsets[p]; ok {
+			fmt.Fprintf(&b, "%d %d\n", p, offset)
+		}
+	}
+
+	return replaceFile(path, []byte(b.String()))
+}
+
+// replaceFile атомарно заменяет содержимое path: tmp файл -> fsync -> rename -> fsync директории.
+// Временный файл начинается с точки: такие имена группам запрещены (см. groupPath), поэтому он не затрет
+// чужую группу, а CreateTemp не даст двум Commit писать в один временный файл.
+func replaceFile(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	// данные на диске до rename
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	// rename - изменение директории, без ее fsync он может потеряться
+	return syncDir(filepath.Dir(path))
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
+
+// Committed возвращает сохраненный offset группы. Если группа еще ничего не коммитила
+// (или записи до коммита уже удалены retention), то читать нужно с Oldest - его и возвращаем.
+func (l *Log) Committed(group string, partition int) (int64, error) {
+	p, err := l.partition(partition)
+	if err != nil {
+		return 0, err
+	}
+	path, err := l.groupPath(group)
+	if err != nil {
+		return 0, err
+	}
+
+	l.groupsMu.Lock()
+	offsets, err := readOffsets(path)
+	l.groupsMu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	offset, ok := offsets[partition]
+	if !ok || offset < p.oldest() {
+		return p.oldest(), nil
+	}
+	return offset, nil
+}
+
+func (l *Log) groupPath(group string) (string, error) {
+	// имена с точки (в том числе "." и "..") заняты временными файлами replaceFile
+	if group == "" || strings.ContainsAny(group, `/\`) || strings.HasPrefix(group, ".") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidGroup, group)
+	}
+	return filepath.Join(l.dir, "groups", group), nil
+}
+
+func readOffsets(path string) (map[int]int64, error) {
+	offsets := make(map[int]int64)
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return offsets, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var partition int
+		var offset int64
+		if _, err := fmt.Sscanf(scanner.Text(), "%d %d", &partition, &offset); err != nil {
+			return nil, fmt.Errorf("group file %s: %w", path, err)
+		}
+		offsets[partition] = offset
+	}
+
+	return offsets, scanner.Err()
+}
@@ -0,0 +1,265 @@
+package commitlog
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Формат сегмента
+//
+// Партиция - это последовательность сегментов. Сегмент - пара файлов с именем по base offset (первому offset в нем):
+//
+//	00000000000000000042.log   - записи подряд
+//	00000000000000000042.index - позиция каждой записи в .log
+//
+// Запись в .log:
+//
+//	length uint32 | crc32 uint32 | offset int64 | time int64 (unix nano) | keyLength int32 | key | value
+//
+// length - размер всего после crc, crc считается по тому же диапазону. keyLength = -1 - ключа нет (nil).
+//
+// Индекс - массив uint32 позиций: позиция записи с offset N лежит в (N - base) * 4. Он плотный (на каждую запись),
+// в Kafka индекс разреженный (запись на каждые N байт) - меньше места, но дочитывать приходится последовательно.
+//
+// После падения последняя запись может быть записана не полностью. Поэтому при открытии активный (последний)
+// сегмент проверяется целиком: все после первой битой записи обрезается, индекс строится заново.
+// Старые сегменты больше не меняются, их проверяет crc при чтении.
+
+const (
+	headerSize  = 4 + 4     // length + crc
+	fixedSize   = 8 + 8 + 4 // offset + time + keyLength
+	indexEntry  = 4         // позиция в .log
+	logSuffix   = ".log"
+	indexSuffix = ".index"
+)
+
+var ErrCorrupted = errors.New("commitlog: record is corrupted")
+
+type segment struct {
+	base    int64
+	next    int64 // offset следующей записи
+	size    int64 // размер .log
+	maxTime time.Time
+
+	log   *os.File
+	index *os.File
+}
+
+func segmentPath(dir string, base int64, suffix string) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", base, suffix))
+}
+
+// openSegment открывает или создает сегмент. active - сегмент, в который будем писать, его проверяем целиком.
+func openSegment(dir string, base int64, active bool) (*segment, error) {
+	logFile, err := os.OpenFile(segmentPath(dir, base, logSuffix), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	indexFile, err := os.OpenFile(segmentPath(dir, base, indexSuffix), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		_ = logFile.Close()
+		return nil, err
+	}
+
+	s := &segment{base: base, next: base, log: logFile, index: indexFile}
+
+	if active {
+		err = s.recover()
+	} else {
+		err = s.load()
+	}
+	if err != nil {
+		_ = s.close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// load читает размеры старого сегмента и время его последней записи (для retention по времени).
+func (s *segment) load() error {
+	logInfo, err := s.log.Stat()
+	if err != nil {
+		return err
+	}
+	indexInfo, err := s.index.Stat()
+	if err != nil {
+		return err
+	}
+
+	s.size = logInfo.Size()
+	s.next = s.base + indexInfo.Size()/indexEntry
+
+	if s.next > s.base {
+		rec, _, err := s.read(s.next - 1)
+		if err != nil {
+			return err
+		}
+		s.maxTime = rec.Time
+	}
+
+	return nil
+}
+
+// recover проходит по .log, обрезает недописанный хвост и строит индекс заново.
+func (s *segment) recover() error {
+	info, err := s.log.Stat()
+	if err != nil {
+		return err
+	}
+
+	var index []byte
+	var pos int64
+	for pos < info.Size() {
+		rec, n, err := s.readAt(pos)
+		if err != nil {
+			break
+		}
+		if rec.Offset != s.next {
+			break
+		}
+
+		index = binary.BigEndian.AppendUint32(index, uint32(pos))
+		s.next++
+		s.maxTime = rec.Time
+		pos += n
+	}
+
+	if err := s.log.Truncate(pos); err != nil {
+		return err
+	}
+	if err := s.index.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := s.index.WriteAt(index, 0); err != nil {
+		return err
+	}
+	s.size = pos
+
+	return nil
+}
+
+func (s *segment) append(rec Record) error {
+	buf := encode(rec)
+
+	if _, err := s.log.WriteAt(buf, s.size); err != nil {
+		return err
+	}
+
+	var entry [indexEntry]byte
+	binary.BigEndian.PutUint32(entry[:], uint32(s.size))
+	if _, err := s.index.WriteAt(entry[:], (rec.Offset-s.base)*indexEntry); err != nil {
+		return err
+	}
+
+	s.size += int64(len(buf))
+	s.next++
+	s.maxTime = rec.Time
+
+	return nil
+}
+
+// read читает запись по offset, возвращает ее и позицию следующей записи.
+func (s *segment) read(offset int64) (Record, int64, error) {
+	var entry [indexEntry]byte
+	if _, err := s.index.ReadAt(entry[:], (offset-s.base)*indexEntry); err != nil {
+		return Record{}, 0, err
+	}
+
+	pos := int64(binary.BigEndian.Uint32(entry[:]))
+	rec, n, err := s.readAt(pos)
+	return rec, pos + n, err
+}
+
+// readAt читает запись с позиции pos в .log, возвращает ее и ее размер.
+func (s *segment) readAt(pos int64) (Record, int64, error) {
+	var header [headerSize]byte
+	if _, err := s.log.ReadAt(header[:], pos); err != nil {
+		return Record{}, 0, corrupted(err)
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length < fixedSize {
+		return Record{}, 0, ErrCorrupted
+	}
+
+	payload := make([]byte, length)
+	if _, err := s.log.ReadAt(payload, pos+headerSize); err != nil {
+		return Record{}, 0, corrupted(err)
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return Record{}, 0, ErrCorrupted
+	}
+
+	rec, err := decode(payload)
+	return rec, headerSize + int64(length), err
+}
+
+func (s *segment) sync() error {
+	return errors.Join(s.log.Sync(), s.index.Sync())
+}
+
+func (s *segment) close() error {
+	return errors.Join(s.log.Close(), s.index.Close())
+}
+
+func (s *segment) remove() error {
+	return errors.Join(s.close(), os.Remove(s.log.Name()), os.Remove(s.index.Name()))
+}
+
+func encode(rec Record) []byte {
+	keyLength := int32(-1)
+	if rec.Key != nil {
+		keyLength = int32(len(rec.Key))
+	}
+
+	length := fixedSize + len(rec.Key) + len(rec.Value)
+	buf := make([]byte, headerSize, headerSize+length)
+
+	binary.BigEndian.PutUint32(buf[0:4], uint32(length))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(rec.Offset))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(rec.Time.UnixNano()))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(keyLength))
+	buf = append(buf, rec.Key...)
+	buf = append(buf, rec.Value...)
+
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(buf[headerSize:]))
+
+	return buf
+}
+
+func decode(payload []byte) (Record, error) {
+	rec := Record{
+		Offset: int64(binary.BigEndian.Uint64(payload[0:8])),
+		Time:   time.Unix(0, int64(binary.BigEndian.Uint64(payload[8:16]))),
+	}
+
+	keyLength := int32(binary.BigEndian.Uint32(payload[16:20]))
+	rest := payload[fixedSize:]
+
+	if keyLength >= 0 {
+		if int(keyLength) > len(rest) {
+			return Record{}, ErrCorrupted
+		}
+		rec.Key = rest[:keyLength]
+		rest = rest[keyLength:]
+	}
+	rec.Value = rest
+
+	return rec, nil
+}
+
+// corrupted - недочитанная запись (обрезанный файл) тоже считается битой.
+func corrupted(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrCorrupted
+	}
+	return err
+}
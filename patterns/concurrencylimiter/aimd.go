@@ -0,0 +1,57 @@
+package concurrencylimiter
+
+import (
+	"time"
+)
+
+// AIMD - additive increase / multiplicative decrease (как в TCP Reno).
+//
+// Идея:
+// Успешное окно из limit запросов - лимит +1, перегрузка (dropped или rtt больше Timeout) - лимит * BackoffRatio.
+// Медленно растем, быстро отступаем, поэтому лимит "пилой" колеблется около реальной емкости downstream.
+//
+// Минусы:
+// Реагирует только на явную перегрузку, на рост latency до Timeout не реагирует.
+type AIMD struct {
+	Initial      int
+	Min          int
+	Max          int
+	BackoffRatio float64       // Во сколько раз уменьшать лимит при перегрузке (0.9)
+	Timeout      time.Duration // Запрос дольше Timeout считается перегрузкой (0 - не учитывать)
+
+	limit        float64
+	lastDecrease time.Time
+}
+
+// NewAIMD создает AIMD с настройками по умолчанию.
+func NewAIMD(initial int) *AIMD {
+	return &AIMD{
+		Initial:      initial,
+		Min:          1,
+		Max:          1000,
+		BackoffRatio: 0.9,
+	}
+}
+
+func (a *AIMD) InitialLimit() int {
+	a.limit = float64(a.Initial)
+	return a.Initial
+}
+
+func (a *AIMD) Update(rtt time.Duration, inflight int, dropped bool) int {
+	if dropped || (a.Timeout > 0 && rtt > a.Timeout) {
+		// Уменьшаем не чаще раза за rtt: все запросы, которые были в полете, перегружены одним и тем же лимитом.
+		// Иначе одна перегрузка уменьшит лимит столько раз, сколько было запросов (аналог "одно уменьшение на окно" в TCP).
+		if now := time.Now(); now.Sub(a.lastDecrease) > rtt {
+			a.limit = max(a.limit*a.BackoffRatio, float64(a.Min))
+			a.lastDecrease = now
+		}
+	} else if inflight*2 >= int(a.limit) {
+		// Растем, только если лимит реально используется. Иначе при малой нагрузке лимит уйдет в Max
+		// и при всплеске перестанет защищать.
+		// +1/limit на запрос = +1 за окно из limit запросов.
+		a.limit = min(a.limit+1/a.limit, float64(a.Max))
+	}
+
+	return int(a.limit)
+}
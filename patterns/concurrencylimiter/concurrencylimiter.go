@@ -0,0 +1,207 @@
+package concurrencylimiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter - адаптивный ограничитель конкурентности (adaptive concurrency limiter).
+//
+// Для чего:
+// Semaphore ограничивает число одновременных запросов фиксированным числом, которое надо угадать заранее.
+// Если downstream замедлился, то фиксированный лимит продолжает его "добивать".
+// Адаптивный лимитер сам подбирает лимит по наблюдаемой latency и ошибкам (по аналогии с TCP congestion control).
+//
+// Идея (Little's law):
+// Пропускная способность = inflight / latency. Пока latency не растет, лимит можно увеличивать.
+// Когда latency растет, значит запросы стоят в очереди у downstream и лимит надо уменьшать.
+//
+// Использование:
+//
+//	token, err := limiter.Acquire(ctx)
+//	if err != nil { ... }
+//	err = call()
+//	if err != nil { token.Dropped() } else { token.Success() }
+//
+// Алгоритм пересчета лимита подключается через Algorithm (AIMD, Gradient).
+type Limiter struct {
+	algo Algorithm
+
+	mu       sync.Mutex
+	limit    int
+	inflight int
+	waiters  []*waiter // FIFO очередь ожидающих
+}
+
+// Algorithm - алгоритм пересчета лимита по результату одного запроса.
+// Вызывается под mutex лимитера, поэтому реализация может не быть thread-safe.
+type Algorithm interface {
+	// InitialLimit - лимит на старте.
+	InitialLimit() int
+
+	// Update принимает результат запроса и возвращает новый лимит.
+	// rtt - время выполнения запроса, inflight - сколько запросов выполнялось в момент его старта,
+	// dropped - запрос завершился перегрузкой (timeout, 503 и т.д.).
+	Update(rtt time.Duration, inflight int, dropped bool) int
+}
+
+type waiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// NewLimiter создает новую структуру.
+func NewLimiter(algo Algorithm) *Limiter {
+	return &Limiter{
+		algo:  algo,
+		limit: max(algo.InitialLimit(), 1),
+	}
+}
+
+// Acquire ждет свободный слот или отмены контекста.
+// Полученный Token обязательно надо завершить одним из методов: Success, Dropped или Ignore.
+func (l *Limiter) Acquire(ctx context.Context) (*Token, error) {
+	l.mu.Lock()
+
+	if ctx.Err() != nil {
+		l.mu.Unlock()
+		return nil, ctx.Err()
+	}
+
+	// Пропускаем без очереди, только если очередь пуста - иначе нарушим FIFO.
+	if len(l.waiters) == 0 && l.inflight < l.limit {
+		token := l.newToken()
+		l.mu.Unlock()
+		return token, nil
+	}
+
+	w := &waiter{ready: make(chan struct{})}
+	l.waiters = append(l.waiters, w)
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		// слот уже учтен в inflight тем, кто нас разбудил
+		return l.tokenFor(), nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if w.granted {
+			// Одновременно с отменой нам отдали слот - возвращаем его следующему.
+			l.inflight--
+			l.wakeUp()
+		} else {
+			l.removeWaiter(w)
+		}
+
+		return nil, ctx.Err()
+	}
+}
+
+// TryAcquire пытается получить слот без ожидания.
+func (l *Limiter) TryAcquire() (*Token, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.waiters) == 0 && l.inflight < l.limit {
+		return l.newToken(), true
+	}
+
+	return nil, false
+}
+
+// Limit возвращает текущий лимит.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.limit
+}
+
+// Inflight возвращает количество выполняющихся запросов.
+func (l *Limiter) Inflight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.inflight
+}
+
+func (l *Limiter) newToken() *Token {
+	l.inflight++
+	return l.tokenFor()
+}
+
+func (l *Limiter) tokenFor() *Token {
+	return &Token{
+		limiter:  l,
+		start:    time.Now(),
+		inflight: l.inflight,
+	}
+}
+
+func (l *Limiter) release(t *Token, update bool, dropped bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inflight--
+
+	if update {
+		l.limit = max(l.algo.Update(time.Since(t.start), t.inflight, dropped), 1)
+	}
+
+	l.wakeUp()
+}
+
+// wakeUp отдает освободившиеся слоты ожидающим в порядке очереди.
+func (l *Limiter) wakeUp() {
+	for len(l.waiters) > 0 && l.inflight < l.limit {
+		w := l.waiters[0]
+		l.waiters = l.waiters[1:]
+
+		l.inflight++
+		w.granted = true
+		close(w.ready)
+	}
+}
+
+func (l *Limiter) removeWaiter(w *waiter) {
+	for i, v := range l.waiters {
+		if v == w {
+			l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// Token - разрешение на выполнение одного запроса.
+type Token struct {
+	limiter  *Limiter
+	start    time.Time
+	inflight int
+	once     sync.Once
+}
+
+// Success - запрос выполнен, его latency учитывается при пересчете лимита.
+func (t *Token) Success() {
+	t.once.Do(func() {
+		t.limiter.release(t, true, false)
+	})
+}
+
+// Dropped - запрос завершился перегрузкой (timeout, отказ downstream), лимит уменьшается.
+func (t *Token) Dropped() {
+	t.once.Do(func() {
+		t.limiter.release(t, true, true)
+	})
+}
+
+// Ignore - запрос не показателен (например, ошибка валидации), слот освобождается без пересчета лимита.
+func (t *Token) Ignore() {
+	t.once.Do(func() {
+		t.limiter.release(t, false, false)
+	})
+}
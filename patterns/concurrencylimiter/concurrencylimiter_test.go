@@ -0,0 +1,249 @@
+package concurrencylimiter
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	t.Run("try_acquire", func(t *testing.T) {
+		l := NewLimiter(fixed(1))
+
+		token, ok := l.TryAcquire()
+		if !ok {
+			t.Fatalf("expected can acquire")
+		}
+
+		if _, ok := l.TryAcquire(); ok {
+			t.Fatalf("expected cannot acquire")
+		}
+
+		token.Ignore()
+
+		if _, ok := l.TryAcquire(); !ok {
+			t.Fatalf("expected can acquire")
+		}
+	})
+
+	t.Run("acquire_waits_for_release", func(t *testing.T) {
+		l := NewLimiter(fixed(1))
+
+		token, _ := l.Acquire(t.Context())
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			token.Success()
+		}()
+
+		ts := time.Now()
+		if _, err := l.Acquire(t.Context()); err != nil {
+			t.Fatalf("got error %v", err)
+		}
+
+		if elapsed := time.Since(ts); elapsed < 15*time.Millisecond {
+			t.Errorf("acquired too early %v", elapsed)
+		}
+	})
+
+	t.Run("context_cancel", func(t *testing.T) {
+		l := NewLimiter(fixed(1))
+		_, _ = l.Acquire(t.Context())
+
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+		defer cancel()
+
+		if _, err := l.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+		}
+
+		if l.Inflight() != 1 {
+			t.Errorf("got %v inflight, want 1", l.Inflight())
+		}
+	})
+
+	t.Run("fifo", func(t *testing.T) {
+		l := NewLimiter(fixed(1))
+		token, _ := l.Acquire(t.Context())
+
+		var order []int
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				tk, _ := l.Acquire(t.Context())
+
+				mu.Lock()
+				order = append(order, i)
+				mu.Unlock()
+
+				tk.Ignore()
+			}(i)
+
+			// ждем, пока goroutine встанет в очередь
+			for waiting(l) != i+1 {
+				time.Sleep(time.Millisecond)
+			}
+		}
+
+		token.Ignore()
+		wg.Wait()
+
+		for i, v := range order {
+			if v != i {
+				t.Fatalf("got %v, want ordered", order)
+			}
+		}
+	})
+
+	t.Run("double_release_is_noop", func(t *testing.T) {
+		l := NewLimiter(fixed(2))
+
+		token, _ := l.Acquire(t.Context())
+		token.Success()
+		token.Dropped()
+
+		if l.Inflight() != 0 {
+			t.Errorf("got %v inflight, want 0", l.Inflight())
+		}
+	})
+}
+
+func TestAIMD(t *testing.T) {
+	a := NewAIMD(10)
+	a.InitialLimit()
+
+	// +1 примерно за окно из limit успешных запросов
+	var got int
+	for i := 0; i < 11; i++ {
+		got = a.Update(time.Millisecond, 10, false)
+	}
+	if got != 11 {
+		t.Errorf("got %v, want 11", got)
+	}
+
+	// лимит не используется - не растет
+	if got := a.Update(time.Millisecond, 1, false); got != 11 {
+		t.Errorf("got %v, want 11", got)
+	}
+
+	// одно уменьшение за rtt, сколько бы запросов ни сообщили о перегрузке
+	for i := 0; i < 5; i++ {
+		got = a.Update(time.Second, 11, true)
+	}
+	if got != 9 {
+		t.Errorf("got %v, want 9", got)
+	}
+}
+
+func TestGradientIgnoresZeroRTT(t *testing.T) {
+	g := NewGradient(10)
+	g.InitialLimit()
+
+	g.Update(0, 10, false)
+	g.Update(-time.Millisecond, 10, false)
+	g.Update(0, 10, true)
+
+	// после некорректных замеров лимит продолжает считаться
+	got := g.Update(time.Millisecond, 10, false)
+	if math.IsNaN(g.limit) || got < g.Min || got > g.Max {
+		t.Errorf("got limit %v (%v), want in [%v, %v]", got, g.limit, g.Min, g.Max)
+	}
+}
+
+// Симуляция: downstream обслуживает capacity запросов параллельно за base, остальные ждут в очереди,
+// поэтому latency растет пропорционально нагрузке. Лимит должен сойтись к capacity, хотя стартует с 100.
+// AIMD с Timeout = 2*base колеблется около 2*capacity, Gradient - около capacity + sqrt(capacity).
+func TestConvergence(t *testing.T) {
+	const capacity = 10
+	const base = 5 * time.Millisecond
+
+	t.Run("aimd", func(t *testing.T) {
+		algo := NewAIMD(100)
+		algo.Timeout = 2 * base
+
+		limit := simulate(t, NewLimiter(algo), capacity, base, algo.Timeout)
+		t.Logf("converged to %v", limit)
+
+		if limit < capacity/2 || limit > 3*capacity {
+			t.Errorf("got limit %v, want about %v", limit, capacity)
+		}
+	})
+
+	t.Run("gradient", func(t *testing.T) {
+		limit := simulate(t, NewLimiter(NewGradient(100)), capacity, base, 0)
+		t.Logf("converged to %v", limit)
+
+		if limit < capacity/2 || limit > 3*capacity {
+			t.Errorf("got limit %v, want about %v", limit, capacity)
+		}
+	})
+}
+
+func simulate(t *testing.T, l *Limiter, capacity int, base time.Duration, timeout time.Duration) int {
+	var inflight atomic.Int64
+
+	server := func() time.Duration {
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+
+		latency := time.Duration(float64(base) * max(1, float64(n)/float64(capacity)))
+		time.Sleep(latency)
+
+		return latency
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 700*time.Millisecond)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				token, err := l.Acquire(ctx)
+				if err != nil {
+					return
+				}
+
+				if latency := server(); timeout > 0 && latency > timeout {
+					token.Dropped()
+				} else {
+					token.Success()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return l.Limit()
+}
+
+func waiting(l *Limiter) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.waiters)
+}
+
+// fixed - алгоритм с постоянным лимитом, для проверки самого Limiter.
+type fixed int
+
+func (f fixed) InitialLimit() int {
+	return int(f)
+}
+
+func (f fixed) Update(time.Duration, int, bool) int {
+	return int(f)
+}
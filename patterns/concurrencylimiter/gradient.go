@@ -0,0 +1,81 @@
+package concurrencylimiter
+
+import (
+	"math"
+	"time"
+)
+
+// Gradient - лимит по отношению минимальной latency к текущей (Vegas/Gradient, как в netflix/concurrency-limits).
+//
+// Идея:
+// minRTT - latency без нагрузки (запрос не стоит в очереди у downstream).
+// gradient = minRTT / rtt: 1 - очереди нет, 0.5 - запрос половину времени простоял в очереди.
+// newLimit = limit * gradient + queueSize, где queueSize = sqrt(limit) - небольшой запас, чтобы нащупывать рост емкости.
+// Лимит сглаживается: limit = limit*(1-Smoothing) + newLimit*Smoothing.
+//
+// Емкость downstream со временем меняется, поэтому minRTT периодически (раз в ProbeInterval замеров)
+// сбрасывается и измеряется заново.
+//
+// Плюсы:
+// Реагирует на рост latency до того, как начнутся timeout.
+type Gradient struct {
+	Initial       int
+	Min           int
+	Max           int
+	Smoothing     float64 // 0..1, насколько быстро лимит следует за расчетным
+	ProbeInterval int     // Через сколько замеров сбрасывать minRTT (0 - никогда)
+
+	limit   float64
+	minRTT  time.Duration
+	samples int
+}
+
+// NewGradient создает Gradient с настройками по умолчанию.
+func NewGradient(initial int) *Gradient {
+	return &Gradient{
+		Initial:       initial,
+		Min:           1,
+		Max:           1000,
+		Smoothing:     0.2,
+		ProbeInterval: 1000,
+	}
+}
+
+func (g *Gradient) InitialLimit() int {
+	g.limit = float64(g.Initial)
+	return g.Initial
+}
+
+func (g *Gradient) Update(rtt time.Duration, inflight int, dropped bool) int {
+	// rtt <= 0 (грубые часы, ошибка замера) дал бы в градиенте NaN или Inf, а NaN проходит через min/max
+	// и портит лимит навсегда. Такой замер игнорируем, для dropped rtt не нужен.
+	if rtt <= 0 && !dropped {
+		return int(g.limit)
+	}
+
+	g.samples++
+	if g.ProbeInterval > 0 && g.samples%g.ProbeInterval == 0 {
+		g.minRTT = 0
+	}
+
+	if rtt > 0 && (g.minRTT == 0 || rtt < g.minRTT) {
+		g.minRTT = rtt
+	}
+
+	// Лимит не используется - данных для роста нет.
+	if !dropped && inflight*2 < int(g.limit) {
+		return int(g.limit)
+	}
+
+	gradient := 0.5
+	if !dropped {
+		gradient = max(0.5, min(1, float64(g.minRTT)/float64(rtt)))
+	}
+
+	newLimit := g.limit*gradient + math.Sqrt(g.limit)
+	newLimit = g.limit*(1-g.Smoothing) + newLimit*g.Smoothing
+
+	g.limit = max(float64(g.Min), min(newLimit, float64(g.Max)))
+
+	return int(g.limit)
+}
@@ -0,0 +1,210 @@
+package fanin
+
+import (
+	"container/heap"
+	"context"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// Tagged - значение вместе с индексом input-канала, из которого оно пришло.
+type Tagged[T any] struct {
+	Source int
+	Value  T
+}
+
+// TaggedMerge - Merge, который сохраняет источник каждого значения.
+//
+// Требования те же, что у Merge: закрывает output после закрытия всех input, реагирует на отмену через контекст.
+func TaggedMerge[T any](ctx context.Context, inputChs ...<-chan T) <-chan Tagged[T] {
+	outputCh := make(chan Tagged[T])
+
+	var wg sync.WaitGroup
+	wg.Add(len(inputChs))
+
+	for i, inputCh := range inputChs {
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case val, ok := <-inputCh:
+					if !ok {
+						return
+					}
+
+					select {
+					case <-ctx.Done():
+						return
+					case outputCh <- Tagged[T]{Source: i, Value: val}:
+					}
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(outputCh)
+	}()
+
+	return outputCh
+}
+
+// PriorityMerge - Merge, в котором inputChs[0] важнее inputChs[1] и т.д.: пока в более важном канале есть значения,
+// менее важные не читаются. Если низкий приоритет льется постоянно, а высокий пуст, то читается низкий.
+//
+// Значения читаются одной goroutine (а не goroutine на канал, как в Merge): иначе каждая goroutine держала бы
+// по уже прочитанному значению, и порядок между каналами решал бы планировщик, а не приоритет.
+//
+// Одно значение низкого приоритета все же может обогнать высокий: если оно уже прочитано и ждет отправки в output,
+// а значение высокого пришло в это время.
+//
+// @idiomatic priority select - сначала неблокирующий select по важному каналу, потом блокирующий по всем
+// @idiomatic reflect.Select - select по заранее неизвестному количеству каналов
+func PriorityMerge[T any](ctx context.Context, inputChs ...<-chan T) <-chan T {
+	outputCh := make(chan T)
+
+	go func() {
+		defer close(outputCh)
+
+		open := slices.Clone(inputChs) // по убыванию приоритета, закрытые удаляются
+
+		for len(open) > 0 {
+			val, i, ok := receivePriority(ctx, open)
+			if ctx.Err() != nil {
+				return
+			}
+			if !ok {
+				open = slices.Delete(open, i, i+1)
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case outputCh <- val:
+			}
+		}
+	}()
+
+	return outputCh
+}
+
+// receivePriority читает из самого важного канала, в котором есть значение.
+// Возвращает индекс канала, ok = false - канал закрыт (или отменен ctx).
+func receivePriority[T any](ctx context.Context, inputChs []<-chan T) (T, int, bool) {
+	// сначала без ожидания, строго по порядку
+	for i, ch := range inputChs {
+		select {
+		case val, ok := <-ch:
+			return val, i, ok
+		default:
+		}
+	}
+
+	// все пусты - ждем первое значение из любого
+	cases := make([]reflect.SelectCase, 0, len(inputChs)+1)
+	for _, ch := range inputChs {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)})
+	}
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+
+	chosen, recv, ok := reflect.Select(cases)
+	if chosen == len(inputChs) || !ok {
+		var zero T
+		return zero, chosen, false
+	}
+
+	// comma-ok: для interface-типа T nil-значение не пройдет обычное приведение
+	val, _ := recv.Interface().(T)
+	return val, chosen, true
+}
+
+// SortedMerge - k-way merge: каждый input отсортирован по cmp, output - тоже (как слияние в merge sort).
+//
+// В heap лежит по одному (текущему) значению из каждого input. Берем минимальное, отправляем и читаем
+// следующее значение из того же input. Поэтому прежде чем отправить первое значение, нужно дождаться
+// значения (или закрытия) от каждого input: медленный input тормозит весь output.
+// Если input не отсортирован, то и output будет не отсортирован (ошибки нет).
+//
+// Закрывает output после закрытия всех input, реагирует на отмену через контекст.
+func SortedMerge[T any](ctx context.Context, cmp func(a, b T) int, inputChs ...<-chan T) <-chan T {
+	outputCh := make(chan T)
+
+	go func() {
+		defer close(outputCh)
+
+		h := &mergeHeap[T]{cmp: cmp}
+
+		// next читает следующее значение input i в heap, false - отменен ctx
+		next := func(i int) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case val, ok := <-inputChs[i]:
+				if ok {
+					heap.Push(h, Tagged[T]{Source: i, Value: val})
+				}
+				return true
+			}
+		}
+
+		for i := range inputChs {
+			if !next(i) {
+				return
+			}
+		}
+
+		for h.Len() > 0 {
+			top := heap.Pop(h).(Tagged[T])
+
+			select {
+			case <-ctx.Done():
+				return
+			case outputCh <- top.Value:
+			}
+
+			if !next(top.Source) {
+				return
+			}
+		}
+	}()
+
+	return outputCh
+}
+
+// mergeHeap - реализация heap.Interface. При равных значениях первым идет input с меньшим индексом,
+// поэтому слияние стабильное.
+type mergeHeap[T any] struct {
+	items []Tagged[T]
+	cmp   func(a, b T) int
+}
+
+func (h *mergeHeap[T]) Len() int {
+	return len(h.items)
+}
+
+func (h *mergeHeap[T]) Less(i, j int) bool {
+	if c := h.cmp(h.items[i].Value, h.items[j].Value); c != 0 {
+		return c < 0
+	}
+	return h.items[i].Source < h.items[j].Source
+}
+
+func (h *mergeHeap[T]) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+}
+
+func (h *mergeHeap[T]) Push(x any) {
+	h.items = append(h.items, x.(Tagged[T]))
+}
+
+func (h *mergeHeap[T]) Pop() any {
+	item := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return item
+}
@@ -0,0 +1,145 @@
+package fanin
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTaggedMerge(t *testing.T) {
+	t.Run("keeps_source", func(t *testing.T) {
+		outputCh := TaggedMerge(t.Context(),
+			makeInputCh([]string{"a1", "a2"}, 0),
+			makeInputCh([]string{"b1"}, 0),
+		)
+
+		got := map[int][]string{}
+		for v := range outputCh {
+			got[v.Source] = append(got[v.Source], v.Value)
+		}
+
+		if !slices.Equal(got[0], []string{"a1", "a2"}) || !slices.Equal(got[1], []string{"b1"}) {
+			t.Errorf("got %v, want a* from 0 and b* from 1", got)
+		}
+	})
+
+	t.Run("context_cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		outputCh := TaggedMerge(ctx, make(chan int))
+
+		cancel()
+		waitClosed(t, outputCh)
+	})
+}
+
+func TestPriorityMerge(t *testing.T) {
+	t.Run("drains_high_priority_first", func(t *testing.T) {
+		high := make(chan int, 3)
+		low := make(chan int, 3)
+		for i := range 3 {
+			high <- 10 + i
+			low <- 20 + i
+		}
+		close(high)
+		close(low)
+
+		got := collect(PriorityMerge(t.Context(), high, low))
+
+		if want := []int{10, 11, 12, 20, 21, 22}; !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("reads_low_when_high_is_empty", func(t *testing.T) {
+		high := make(chan int)
+		low := makeInputCh([]int{1, 2}, 0)
+
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+
+		outputCh := PriorityMerge(ctx, high, low)
+		if got := []int{<-outputCh, <-outputCh}; !slices.Equal(got, []int{1, 2}) {
+			t.Errorf("got %v, want [1 2]", got)
+		}
+	})
+
+	t.Run("context_cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		outputCh := PriorityMerge(ctx, make(chan int), make(chan int))
+
+		cancel()
+		waitClosed(t, outputCh)
+	})
+}
+
+func TestSortedMerge(t *testing.T) {
+	t.Run("globally_sorted", func(t *testing.T) {
+		outputCh := SortedMerge(t.Context(), cmp.Compare[int],
+			makeInputCh([]int{1, 4, 7, 10}, 0),
+			makeInputCh([]int{2, 5, 8}, 2),
+			makeInputCh([]int{}, 0),
+			makeInputCh([]int{0, 3, 6, 9, 11}, 0),
+		)
+
+		got := collect(outputCh)
+		if !slices.IsSorted(got) || len(got) != 12 {
+			t.Errorf("got %v, want 0..11", got)
+		}
+	})
+
+	t.Run("stable_for_equal_values", func(t *testing.T) {
+		type item struct {
+			key    int
+			source string
+		}
+		byKey := func(a, b item) int {
+			return cmp.Compare(a.key, b.key)
+		}
+
+		outputCh := SortedMerge(t.Context(), byKey,
+			makeInputCh([]item{{1, "a"}, {2, "a"}}, 0),
+			makeInputCh([]item{{1, "b"}, {2, "b"}}, 0),
+		)
+
+		want := []item{{1, "a"}, {1, "b"}, {2, "a"}, {2, "b"}}
+		if got := collect(outputCh); !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("context_cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+
+		// второй input молчит, поэтому первое значение не может быть отправлено
+		outputCh := SortedMerge(ctx, cmp.Compare[int], makeInputCh([]int{1}, 0), make(chan int))
+
+		cancel()
+		waitClosed(t, outputCh)
+	})
+}
+
+func collect[T any](ch <-chan T) []T {
+	var res []T
+	for v := range ch {
+		res = append(res, v)
+	}
+	return res
+}
+
+func waitClosed[T any](t *testing.T, ch <-chan T) {
+	t.Helper()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("output is not closed after cancel")
+		}
+	}
+}
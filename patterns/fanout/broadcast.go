@@ -0,0 +1,252 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrBroadcasterClosed = errors.New("broadcaster is closed")
+	ErrSlowSubscriber    = errors.New("subscriber is too slow")
+	ErrUnsubscribed      = errors.New("unsubscribed")
+)
+
+// OverflowPolicy - что делать, если буфер подписчика заполнен.
+type OverflowPolicy int
+
+const (
+	// Block - ждать, пока подписчик освободит место. Медленный подписчик тормозит Publish (как FanOut).
+	Block OverflowPolicy = iota
+	// DropNewest - отбросить новое значение.
+	DropNewest
+	// DropOldest - отбросить самое старое значение из буфера и добавить новое (подписчик видит свежие данные).
+	DropOldest
+	// Disconnect - отписать подписчика и закрыть его канал, причина в Subscription.Err.
+	Disconnect
+)
+
+// Broadcaster раздает каждое опубликованное значение всем текущим подписчикам.
+//
+// В отличие от FanOut:
+//   - подписаться и отписаться можно в любой момент
+//   - у каждого подписчика свой буфер и своя OverflowPolicy, поэтому медленный подписчик
+//     с политикой, отличной от Block, не тормозит остальных
+//
+// Publish вызовы выполняются по очереди, поэтому все подписчики видят значения в одном порядке.
+//
+// @idiomatic буферизированный канал как очередь подписчика, select + default для неблокирующей записи
+type Broadcaster[T any] struct {
+	mu     sync.Mutex // защищает subs и closed
+	subs   map[*Subscription[T]]struct{}
+	closed bool
+
+	// publishMu упорядочивает Publish и закрытие каналов подписчиков: закрыть канал,
+	// в который в этот момент пишет Publish, нельзя (panic)
+	publishMu sync.Mutex
+}
+
+func NewBroadcaster[T any]() *Broadcaster[T] {
+	return &Broadcaster[T]{
+		subs: make(map[*Subscription[T]]struct{}),
+	}
+}
+
+// Subscription - подписка на Broadcaster. Значения читаются из C, канал закрывается при отписке.
+type Subscription[T any] struct {
+	C <-chan T
+
+	ch     chan T
+	policy OverflowPolicy
+	done   chan struct{} // закрывается при отписке, чтобы разблокировать Publish
+	once   sync.Once
+
+	dropped atomic.Uint64
+	err     atomic.Pointer[error]
+}
+
+// Lag - сколько значений ждут в буфере подписчика.
+func (s *Subscription[T]) Lag() int {
+	return len(s.ch)
+}
+
+// Dropped - сколько значений подписчик потерял из-за переполнения буфера.
+func (s *Subscription[T]) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Err - причина закрытия C: ErrUnsubscribed, ErrSlowSubscriber или ErrBroadcasterClosed. nil - подписка активна.
+func (s *Subscription[T]) Err() error {
+	if err := s.err.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// Subscribe добавляет подписчика с буфером на bufferSize значений.
+// Подписчик получает только значения, опубликованные после подписки.
+// Если Broadcaster уже закрыт, то возвращается закрытая подписка.
+func (b *Broadcaster[T]) Subscribe(bufferSize int, policy OverflowPolicy) *Subscription[T] {
+	if bufferSize < 0 {
+		panic("bufferSize must not be negative")
+	}
+
+	ch := make(chan T, bufferSize)
+	s := &Subscription[T]{
+		C:      ch,
+		ch:     ch,
+		policy: policy,
+		done:   make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		s.stop(ErrBroadcasterClosed)
+		close(s.ch)
+		return s
+	}
+
+	b.subs[s] = struct{}{}
+	return s
+}
+
+// Unsubscribe отписывает подписчика и закрывает его канал. Значения, оставшиеся в буфере, можно дочитать.
+func (b *Broadcaster[T]) Unsubscribe(s *Subscription[T]) {
+	// сначала разблокировать Publish, который может ждать этого подписчика
+	s.stop(ErrUnsubscribed)
+
+	b.publishMu.Lock()
+	defer b.publishMu.Unlock()
+
+	b.remove(s)
+}
+
+// Publish отправляет val всем подписчикам согласно их OverflowPolicy.
+// Блокируется только на подписчиках с Block, отмена ctx прерывает ожидание.
+func (b *Broadcaster[T]) Publish(ctx context.Context, val T) error {
+	b.publishMu.Lock()
+	defer b.publishMu.Unlock()
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrBroadcasterClosed
+	}
+	subs := make([]*Subscription[T], 0, len(b.subs))
+	for s := range b.subs {
+		subs = append(subs, s)
+	}
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		if err := b.deliver(ctx, s, val); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run публикует все значения из inputCh, а после его закрытия закрывает Broadcaster.
+func (b *Broadcaster[T]) Run(ctx context.Context, inputCh <-chan T) error {
+	defer b.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case val, ok := <-inputCh:
+			if !ok {
+				return nil
+			}
+			if err := b.Publish(ctx, val); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Close отписывает всех подписчиков. Дальнейшие Publish возвращают ErrBroadcasterClosed.
+func (b *Broadcaster[T]) Close() {
+	b.mu.Lock()
+	b.closed = true
+	subs := make([]*Subscription[T], 0, len(b.subs))
+	for s := range b.subs {
+		subs = append(subs, s)
+		s.stop(ErrBroadcasterClosed)
+	}
+	b.mu.Unlock()
+
+	b.publishMu.Lock()
+	defer b.publishMu.Unlock()
+
+	for _, s := range subs {
+		b.remove(s)
+	}
+}
+
+// deliver вызывается под publishMu.
+func (b *Broadcaster[T]) deliver(ctx context.Context, s *Subscription[T], val T) error {
+	select {
+	case <-s.done:
+		return nil
+	case s.ch <- val:
+		return nil
+	default:
+	}
+
+	switch s.policy {
+	case DropNewest:
+		s.dropped.Add(1)
+	case DropOldest:
+		// пишет в канал только Publish, поэтому после чтения место точно есть,
+		// но подписчик мог успеть освободить его сам - тогда ничего не теряем
+		select {
+		case <-s.ch:
+			s.dropped.Add(1)
+		default:
+		}
+		select {
+		case s.ch <- val:
+		default:
+			// bufferSize == 0: буфера нет, значение получит только ждущий подписчик
+			s.dropped.Add(1)
+		}
+	case Disconnect:
+		s.dropped.Add(1)
+		s.stop(ErrSlowSubscriber)
+		b.remove(s)
+	default:
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.done:
+		case s.ch <- val:
+		}
+	}
+
+	return nil
+}
+
+// remove удаляет подписчика и закрывает его канал, вызывается под publishMu.
+func (b *Broadcaster[T]) remove(s *Subscription[T]) {
+	b.mu.Lock()
+	_, ok := b.subs[s]
+	delete(b.subs, s)
+	b.mu.Unlock()
+
+	if ok {
+		close(s.ch)
+	}
+}
+
+// stop запоминает первую причину отписки и разблокирует ожидающий Publish.
+func (s *Subscription[T]) stop(err error) {
+	s.once.Do(func() {
+		s.err.Store(&err)
+		close(s.done)
+	})
+}
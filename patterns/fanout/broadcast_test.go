@@ -0,0 +1,181 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBroadcaster(t *testing.T) {
+	t.Run("all_subscribers_get_all_values", func(t *testing.T) {
+		ctx := t.Context()
+		b := NewBroadcaster[int]()
+
+		s1 := b.Subscribe(3, Block)
+		s2 := b.Subscribe(3, Block)
+
+		for i := 1; i <= 3; i++ {
+			if err := b.Publish(ctx, i); err != nil {
+				t.Fatal(err)
+			}
+		}
+		b.Close()
+
+		for _, s := range []*Subscription[int]{s1, s2} {
+			if got := drain(s); !slices.Equal(got, []int{1, 2, 3}) {
+				t.Errorf("got %v, want [1 2 3]", got)
+			}
+			if !errors.Is(s.Err(), ErrBroadcasterClosed) {
+				t.Errorf("got %v, want ErrBroadcasterClosed", s.Err())
+			}
+		}
+	})
+
+	t.Run("subscribe_later", func(t *testing.T) {
+		ctx := t.Context()
+		b := NewBroadcaster[int]()
+
+		_ = b.Publish(ctx, 1)
+		s := b.Subscribe(1, Block)
+		_ = b.Publish(ctx, 2)
+		b.Close()
+
+		if got := drain(s); !slices.Equal(got, []int{2}) {
+			t.Errorf("got %v, want [2]", got)
+		}
+	})
+
+	t.Run("drop_newest", func(t *testing.T) {
+		ctx := t.Context()
+		b := NewBroadcaster[int]()
+
+		s := b.Subscribe(2, DropNewest)
+		for i := 1; i <= 5; i++ {
+			_ = b.Publish(ctx, i)
+		}
+
+		if s.Lag() != 2 || s.Dropped() != 3 {
+			t.Errorf("got lag %v dropped %v, want 2 and 3", s.Lag(), s.Dropped())
+		}
+
+		b.Close()
+		if got := drain(s); !slices.Equal(got, []int{1, 2}) {
+			t.Errorf("got %v, want [1 2]", got)
+		}
+	})
+
+	t.Run("drop_oldest", func(t *testing.T) {
+		ctx := t.Context()
+		b := NewBroadcaster[int]()
+
+		s := b.Subscribe(2, DropOldest)
+		for i := 1; i <= 5; i++ {
+			_ = b.Publish(ctx, i)
+		}
+
+		if s.Dropped() != 3 {
+			t.Errorf("got dropped %v, want 3", s.Dropped())
+		}
+
+		b.Close()
+		if got := drain(s); !slices.Equal(got, []int{4, 5}) {
+			t.Errorf("got %v, want [4 5]", got)
+		}
+	})
+
+	t.Run("disconnect_slow_does_not_block_others", func(t *testing.T) {
+		ctx := t.Context()
+		b := NewBroadcaster[int]()
+
+		slow := b.Subscribe(1, Disconnect)
+		fast := b.Subscribe(10, Block)
+
+		for i := 1; i <= 3; i++ {
+			if err := b.Publish(ctx, i); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if got := drain(slow); !slices.Equal(got, []int{1}) {
+			t.Errorf("got %v, want [1]", got)
+		}
+		if !errors.Is(slow.Err(), ErrSlowSubscriber) {
+			t.Errorf("got %v, want ErrSlowSubscriber", slow.Err())
+		}
+
+		b.Close()
+		if got := drain(fast); !slices.Equal(got, []int{1, 2, 3}) {
+			t.Errorf("got %v, want [1 2 3]", got)
+		}
+	})
+
+	t.Run("unsubscribe_unblocks_publish", func(t *testing.T) {
+		ctx := t.Context()
+		b := NewBroadcaster[int]()
+
+		s := b.Subscribe(0, Block)
+
+		published := make(chan error)
+		go func() {
+			published <- b.Publish(ctx, 1)
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		b.Unsubscribe(s)
+
+		select {
+		case err := <-published:
+			if err != nil {
+				t.Errorf("got %v, want nil", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("publish is still blocked")
+		}
+
+		if _, ok := <-s.C; ok {
+			t.Error("channel is not closed")
+		}
+		if !errors.Is(s.Err(), ErrUnsubscribed) {
+			t.Errorf("got %v, want ErrUnsubscribed", s.Err())
+		}
+	})
+
+	t.Run("publish_context_cancel", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+		defer cancel()
+
+		b := NewBroadcaster[int]()
+		b.Subscribe(0, Block)
+
+		if err := b.Publish(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got %v, want context.DeadlineExceeded", err)
+		}
+	})
+
+	t.Run("run_closes_on_input_close", func(t *testing.T) {
+		ctx := t.Context()
+		b := NewBroadcaster[int]()
+		s := b.Subscribe(3, Block)
+
+		if err := b.Run(ctx, makeInputCh([]int{1, 2, 3}, 0)); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := drain(s); !slices.Equal(got, []int{1, 2, 3}) {
+			t.Errorf("got %v, want [1 2 3]", got)
+		}
+		if err := b.Publish(ctx, 4); !errors.Is(err, ErrBroadcasterClosed) {
+			t.Errorf("got %v, want ErrBroadcasterClosed", err)
+		}
+	})
+}
+
+func drain[T any](s *Subscription[T]) []T {
+	var res []T
+	for val := range s.C {
+		res = append(res, val)
+	}
+	return res
+}
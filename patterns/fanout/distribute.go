@@ -0,0 +1,257 @@
+package fanout
+
+import (
+	"cmp"
+	"context"
+	"hash/fnv"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Strategy - как Distribute выбирает output для значения. Создается через RoundRobin, LeastLoaded,
+// ConsistentHash или Random.
+type Strategy[T any] interface {
+	// order возвращает индексы из alive в порядке предпочтения для val.
+	// Если вернуть один индекс, то значение уйдет только в этот output (нужно для ConsistentHash).
+	order(val T, outputChs []chan<- T, alive []int) []int
+}
+
+// Distribute - в отличие от FanOut, каждое значение получает только один из output-каналов (балансировка нагрузки).
+//
+// Требования:
+//   - не закрывает никакие каналы
+//   - реагирует на отмену через контекст
+//   - медленный output не тормозит остальные: если предпочтительный output занят, значение уходит
+//     в следующий свободный, а если заняты все, то в тот, который освободится первым
+//   - читатель, который уходит, сообщает об этом через Distributor.Remove, и значения перераспределяются
+//     на остальных. Закрывать output можно только после Remove: канал закрывает писатель, а не читатель,
+//     и отправка в закрытый канал - panic
+//   - завершается после закрытия input или когда не осталось ни одного output
+//
+// @idiomatic reflect.Select - select по заранее неизвестному количеству каналов
+func Distribute[T any](ctx context.Context, inputCh <-chan T, strategy Strategy[T], outputChs ...chan<- T) *Distributor {
+	d := &Distributor{
+		removeCh: make(chan int),
+		done:     make(chan struct{}),
+	}
+
+	alive := make([]int, len(outputChs))
+	for i := range outputChs {
+		alive[i] = i
+	}
+
+	remove := func(i int) {
+		alive = slices.DeleteFunc(alive, func(a int) bool {
+			return a == i
+		})
+	}
+
+	go func() {
+		defer close(d.done)
+
+		for len(alive) > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case i := <-d.removeCh:
+				remove(i)
+			case val, ok := <-inputCh:
+				if !ok {
+					return
+				}
+
+				var delivered bool
+				for !delivered && len(alive) > 0 {
+					order := strategy.order(val, outputChs, alive)
+
+					var removed int
+					delivered, removed = deliver(ctx, d.removeCh, outputChs, order, val)
+					if ctx.Err() != nil {
+						return
+					}
+					if removed >= 0 {
+						remove(removed)
+					}
+				}
+			}
+		}
+	}()
+
+	return d
+}
+
+// Distributor управляет запущенным Distribute.
+type Distributor struct {
+	removeCh chan int
+	done     chan struct{} // закрывается, когда Distribute завершился
+}
+
+// Remove исключает outputChs[i] из распределения. После возврата Distribute больше не пишет в этот канал,
+// и его можно закрыть. Если Distribute уже завершился, то ничего не делает.
+func (d *Distributor) Remove(i int) {
+	select {
+	case d.removeCh <- i:
+	case <-d.done:
+	}
+}
+
+// deliver отправляет val в первый свободный output из order.
+// Если во время ожидания пришел Remove, то значение не отправлено и возвращается индекс удаленного output
+// (его нужно убрать и отправить значение заново), иначе -1.
+func deliver[T any](ctx context.Context, removeCh <-chan int, outputChs []chan<- T, order []int, val T) (bool, int) {
+	// сначала без ожидания, в порядке предпочтения
+	for _, i := range order {
+		select {
+		case outputChs[i] <- val:
+			return true, -1
+		default:
+		}
+	}
+
+	// все заняты - ждем первый освободившийся
+	cases := make([]reflect.SelectCase, 0, len(order)+2)
+	cases = append(cases,
+		reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(removeCh)},
+	)
+	for _, i := range order {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectSend, Chan: reflect.ValueOf(outputChs[i]), Send: reflect.ValueOf(val)})
+	}
+
+	chosen, recv, _ := reflect.Select(cases)
+	switch chosen {
+	case 0:
+		return false, -1
+	case 1:
+		return false, int(recv.Int())
+	default:
+		return true, -1
+	}
+}
+
+type roundRobin[T any] struct {
+	next atomic.Uint64
+}
+
+// RoundRobin - по очереди, начиная со следующего после предыдущего output.
+func RoundRobin[T any]() Strategy[T] {
+	return &roundRobin[T]{}
+}
+
+func (s *roundRobin[T]) order(_ T, _ []chan<- T, alive []int) []int {
+	start := int(s.next.Add(1)-1) % len(alive)
+	return append(slices.Clone(alive[start:]), alive[:start]...)
+}
+
+type leastLoaded[T any] struct {
+	next atomic.Uint64
+}
+
+// LeastLoaded - в output с самым коротким буфером (len(ch)). Для небуферизированных каналов
+// длина всегда 0, и значение уходит в первый готовый читатель.
+func LeastLoaded[T any]() Strategy[T] {
+	return &leastLoaded[T]{}
+}
+
+func (s *leastLoaded[T]) order(_ T, outputChs []chan<- T, alive []int) []int {
+	// сдвиг, чтобы при равной загрузке не выбирать всегда первый
+	start := int(s.next.Add(1)-1) % len(alive)
+	order := append(slices.Clone(alive[start:]), alive[:start]...)
+
+	slices.SortStableFunc(order, func(a, b int) int {
+		return len(outputChs[a]) - len(outputChs[b])
+	})
+	return order
+}
+
+type random[T any] struct{}
+
+// Random - в случайный output.
+func Random[T any]() Strategy[T] {
+	return random[T]{}
+}
+
+func (random[T]) order(_ T, _ []chan<- T, alive []int) []int {
+	order := slices.Clone(alive)
+	rand.Shuffle(len(order), func(i, j int) {
+		order[i], order[j] = order[j], order[i]
+	})
+	return order
+}
+
+type consistentHash[T any] struct {
+	key      func(T) string
+	replicas int
+
+	mu    sync.Mutex
+	rings map[int][]ringNode // по количеству outputs: одну стратегию можно передать в несколько Distribute
+}
+
+type ringNode struct {
+	hash   uint64
+	output int
+}
+
+// ConsistentHash - значения с одинаковым ключом всегда уходят в один и тот же output (например, чтобы события
+// одного пользователя обрабатывал один worker по порядку). Поэтому занятый output не пропускается, а ожидается.
+//
+// Каждый output занимает replicas точек на кольце хешей, ключ попадает в первую точку по часовой стрелке.
+// Если output удален, то его ключи переходят к соседям по кольцу, а ключи остальных outputs не перемещаются.
+func ConsistentHash[T any](replicas int, key func(T) string) Strategy[T] {
+	if replicas <= 0 {
+		panic("replicas must be greater than 0")
+	}
+
+	return &consistentHash[T]{key: key, replicas: replicas, rings: make(map[int][]ringNode)}
+}
+
+func (s *consistentHash[T]) order(val T, outputChs []chan<- T, alive []int) []int {
+	ring := s.ring(len(outputChs))
+
+	h := hashKey(s.key(val))
+	start, _ := slices.BinarySearchFunc(ring, h, func(n ringNode, h uint64) int {
+		return cmp.Compare(n.hash, h)
+	})
+
+	for i := range ring {
+		node := ring[(start+i)%len(ring)]
+		if slices.Contains(alive, node.output) {
+			return []int{node.output}
+		}
+	}
+
+	return nil
+}
+
+// ring - кольцо по всем n outputs, строится один раз для каждого n. Удаленные outputs просто пропускаются.
+func (s *consistentHash[T]) ring(n int) []ringNode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ring, ok := s.rings[n]; ok {
+		return ring
+	}
+
+	ring := make([]ringNode, 0, n*s.replicas)
+	for output := range n {
+		for r := range s.replicas {
+			ring = append(ring, ringNode{hash: hashKey(strconv.Itoa(output) + "#" + strconv.Itoa(r)), output: output})
+		}
+	}
+	slices.SortFunc(ring, func(a, b ringNode) int {
+		return cmp.Compare(a.hash, b.hash)
+	})
+
+	s.rings[n] = ring
+	return ring
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum64()
+}
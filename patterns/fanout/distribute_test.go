@@ -0,0 +1,250 @@
+package fanout
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDistribute(t *testing.T) {
+	vals := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+
+	strategies := map[string]func() Strategy[int]{
+		"round_robin":  RoundRobin[int],
+		"least_loaded": LeastLoaded[int],
+		"random":       Random[int],
+		"consistent_hash": func() Strategy[int] {
+			return ConsistentHash(16, func(v int) string { return strconv.Itoa(v) })
+		},
+	}
+
+	for name, strategy := range strategies {
+		t.Run(name+"_delivers_each_value_once", func(t *testing.T) {
+			outputChs := []chan int{make(chan int, 20), make(chan int, 20), make(chan int, 20)}
+
+			Distribute(t.Context(), makeInputCh(vals, 0), strategy(), sendOnly(outputChs)...)
+
+			got := receiveN(t, len(vals), outputChs...)
+			slices.Sort(got)
+			if !slices.Equal(got, vals) {
+				t.Errorf("got %v, want %v", got, vals)
+			}
+		})
+
+		t.Run(name+"_skips_removed", func(t *testing.T) {
+			outputChs := []chan int{make(chan int, 20), make(chan int, 20)}
+			inputCh := make(chan int)
+
+			d := Distribute(t.Context(), inputCh, strategy(), sendOnly(outputChs)...)
+			d.Remove(0)
+			// после Remove канал можно закрыть
+			close(outputChs[0])
+
+			go func() {
+				for _, v := range vals {
+					inputCh <- v
+				}
+			}()
+
+			got := receiveN(t, len(vals), outputChs[1])
+			if len(got) != len(vals) {
+				t.Errorf("got %v, want %v values", len(got), len(vals))
+			}
+		})
+	}
+
+	t.Run("round_robin_order", func(t *testing.T) {
+		outputChs := []chan int{make(chan int, 10), make(chan int, 10), make(chan int, 10)}
+
+		Distribute(t.Context(), makeInputCh(vals[:6], 0), RoundRobin[int](), sendOnly(outputChs)...)
+		waitBuffered(t, 6, outputChs...)
+
+		for i, ch := range outputChs {
+			got := []int{<-ch, <-ch}
+			if want := []int{i + 1, i + 4}; !slices.Equal(got, want) {
+				t.Errorf("got %v in output %v, want %v", got, i, want)
+			}
+		}
+	})
+
+	t.Run("slow_output_does_not_block", func(t *testing.T) {
+		// первый output никто не читает, все значения должны уйти во второй
+		slow := make(chan int)
+		fast := make(chan int)
+
+		Distribute(t.Context(), makeInputCh(vals, 0), RoundRobin[int](), slow, fast)
+
+		got := receiveN(t, len(vals), fast)
+		if len(got) != len(vals) {
+			t.Errorf("got %v, want %v values", len(got), len(vals))
+		}
+	})
+
+	t.Run("least_loaded_prefers_shortest_buffer", func(t *testing.T) {
+		busy := make(chan int, 10)
+		idle := make(chan int, 10)
+		busy <- 0
+		busy <- 0
+
+		Distribute(t.Context(), makeInputCh([]int{1, 2}, 0), LeastLoaded[int](), busy, idle)
+
+		receiveN(t, 2, idle)
+		if len(busy) != 2 {
+			t.Errorf("got %v values in busy output, want 2", len(busy))
+		}
+	})
+
+	t.Run("consistent_hash_same_key_same_output", func(t *testing.T) {
+		outputChs := []chan int{make(chan int, 20), make(chan int, 20), make(chan int, 20)}
+
+		// ключ - остаток от деления на 4
+		strategy := ConsistentHash(16, func(v int) string { return strconv.Itoa(v % 4) })
+		Distribute(t.Context(), makeInputCh(vals, 0), strategy, sendOnly(outputChs)...)
+		waitBuffered(t, len(vals), outputChs...)
+
+		outputOfKey := make(map[int]int)
+		for i, ch := range outputChs {
+			for range len(ch) {
+				key := <-ch % 4
+				if prev, ok := outputOfKey[key]; ok && prev != i {
+					t.Errorf("got key %v in outputs %v and %v", key, prev, i)
+				}
+				outputOfKey[key] = i
+			}
+		}
+	})
+
+	t.Run("remove_while_waiting", func(t *testing.T) {
+		// оба output заняты, значение ждет в select
+		gone := make(chan int)
+		stays := make(chan int)
+
+		d := Distribute(t.Context(), makeInputCh([]int{1}, 0), RoundRobin[int](), gone, stays)
+		time.Sleep(10 * time.Millisecond)
+
+		d.Remove(0)
+		close(gone)
+
+		if got := receiveN(t, 1, stays); got[0] != 1 {
+			t.Errorf("got %v, want 1", got[0])
+		}
+	})
+
+	t.Run("remove_after_finish", func(t *testing.T) {
+		inputCh := make(chan int)
+		close(inputCh)
+
+		d := Distribute(t.Context(), inputCh, RoundRobin[int](), make(chan int))
+		<-d.done
+
+		// не блокируется
+		d.Remove(0)
+	})
+
+	t.Run("consistent_hash_reused_with_other_outputs", func(t *testing.T) {
+		key := func(v int) string { return strconv.Itoa(v) }
+		reused := ConsistentHash(16, key)
+		fresh := ConsistentHash(16, key)
+
+		two := sendOnly([]chan int{make(chan int), make(chan int)})
+		three := sendOnly([]chan int{make(chan int), make(chan int), make(chan int)})
+
+		reused.order(1, two, []int{0, 1})
+
+		// кольцо для 3 outputs не зависит от того, с каким количеством стратегию использовали раньше
+		for _, v := range vals {
+			got := reused.order(v, three, []int{0, 1, 2})
+			want := fresh.order(v, three, []int{0, 1, 2})
+			if !slices.Equal(got, want) {
+				t.Errorf("got %v for key %v, want %v", got, v, want)
+			}
+		}
+	})
+
+	t.Run("context_cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+
+		inputCh := make(chan int)
+		outputCh := make(chan int)
+		Distribute(ctx, inputCh, RoundRobin[int](), outputCh)
+
+		go func() {
+			inputCh <- 1
+		}()
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+
+		select {
+		case <-outputCh:
+			t.Error("got value after cancel")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+}
+
+func sendOnly[T any](chs []chan T) []chan<- T {
+	res := make([]chan<- T, len(chs))
+	for i, ch := range chs {
+		res[i] = ch
+	}
+	return res
+}
+
+// receiveN читает n значений из всех каналов.
+func receiveN[T any](t *testing.T, n int, chs ...chan T) []T {
+	t.Helper()
+
+	var mu sync.Mutex
+	var res []T
+	done := make(chan struct{})
+
+	for _, ch := range chs {
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				case v := <-ch:
+					mu.Lock()
+					res = append(res, v)
+					if len(res) == n {
+						close(done)
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for %v values", n)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return res
+}
+
+// waitBuffered ждет, пока в буферах каналов не окажется n значений.
+func waitBuffered[T any](t *testing.T, n int, chs ...chan T) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		total := 0
+		for _, ch := range chs {
+			total += len(ch)
+		}
+		if total == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("timeout waiting for %v values", n)
+}
@@ -2,7 +2,12 @@ package firstresult
 
 import (
 	"context"
+	"errors"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestWay11(t *testing.T) {
@@ -39,6 +44,180 @@ func TestWay21(t *testing.T) {
 	})
 }
 
+func TestHedge(t *testing.T) {
+	t.Run("fast_first_attempt_should_not_hedge", func(t *testing.T) {
+		var calls atomic.Int32
+
+		val, err := Hedge(t.Context(), func(ctx context.Context, attempt int) (int, error) {
+			calls.Add(1)
+			return attempt, nil
+		}, HedgeConfig{Delay: FixedDelay(time.Second), MaxHedges: 2})
+
+		if err != nil || val != 0 {
+			t.Fatalf("got %v, %v, want 0, nil", val, err)
+		}
+
+		if calls.Load() != 1 {
+			t.Errorf("got %v calls, want 1", calls.Load())
+		}
+	})
+
+	t.Run("slow_first_attempt_should_hedge", func(t *testing.T) {
+		c := clock.NewFake()
+		canceled := make(chan struct{})
+
+		done := make(chan int, 1)
+		go func() {
+			val, _ := Hedge(t.Context(), func(ctx context.Context, attempt int) (int, error) {
+				if attempt == 0 {
+					// зависла, должна быть отменена после победы второй
+					<-ctx.Done()
+					close(canceled)
+					return 0, ctx.Err()
+				}
+				return attempt, nil
+			}, HedgeConfig{Delay: FixedDelay(100 * time.Millisecond), MaxHedges: 2, Clock: c})
+			done <- val
+		}()
+
+		c.BlockUntil(1)
+		c.Advance(100 * time.Millisecond)
+
+		if val := <-done; val != 1 {
+			t.Errorf("got %v, want 1", val)
+		}
+
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			t.Fatalf("first attempt was not canceled")
+		}
+	})
+
+	t.Run("all_failed", func(t *testing.T) {
+		var calls atomic.Int32
+		errA := errors.New("a")
+
+		_, err := Hedge(t.Context(), func(ctx context.Context, attempt int) (int, error) {
+			calls.Add(1)
+			return 0, errA
+		}, HedgeConfig{MaxHedges: 2})
+
+		// ошибка запускает следующую попытку сразу, но не больше 1 + MaxHedges
+		if calls.Load() != 3 {
+			t.Errorf("got %v calls, want 3", calls.Load())
+		}
+
+		if !errors.Is(err, errA) || !strings.Contains(err.Error(), "attempt 2") {
+			t.Errorf("got %v, want errors of all attempts", err)
+		}
+	})
+
+	t.Run("percentile_delay", func(t *testing.T) {
+		d := NewPercentileDelay(0.9, 10, time.Second)
+		if d.Delay() != time.Second {
+			t.Fatalf("got %v, want fallback", d.Delay())
+		}
+
+		for i := 1; i <= 20; i++ {
+			d.Observe(time.Duration(i) * time.Millisecond)
+		}
+
+		// окно хранит 11..20ms
+		if got := d.Delay(); got != 19*time.Millisecond {
+			t.Errorf("got %v, want 19ms", got)
+		}
+	})
+
+	t.Run("negative_max_hedges_panics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("got no panic")
+			}
+		}()
+
+		_, _ = Hedge(t.Context(), func(ctx context.Context, attempt int) (int, error) {
+			return 1, nil
+		}, HedgeConfig{MaxHedges: -1})
+	})
+}
+
+func TestQuorum(t *testing.T) {
+	addresses := []string{"1", "2", "3", "4", "5"}
+
+	t.Run("last_write_wins", func(t *testing.T) {
+		versions := map[string]int64{"1": 1, "2": 3, "3": 2, "4": 3, "5": 3}
+
+		val, err := Quorum(t.Context(), addresses, 5, func(ctx context.Context, address string) (Versioned[string], error) {
+			return Versioned[string]{Value: "from " + address, Version: versions[address]}, nil
+		}, LastWriteWins[string])
+
+		if err != nil {
+			t.Fatalf("got error %v", err)
+		}
+
+		if val.Version != 3 {
+			t.Errorf("got %+v, want version 3", val)
+		}
+	})
+
+	t.Run("cancels_rest", func(t *testing.T) {
+		var canceled atomic.Int32
+		release := make(chan struct{})
+		defer close(release)
+
+		_, err := Quorum(t.Context(), addresses, 3, func(ctx context.Context, address string) (string, error) {
+			if address > "3" {
+				// медленные реплики
+				select {
+				case <-ctx.Done():
+					canceled.Add(1)
+				case <-release:
+				}
+				return "", ctx.Err()
+			}
+			return address, nil
+		}, nil)
+
+		if err != nil {
+			t.Fatalf("got error %v", err)
+		}
+
+		for canceled.Load() != 2 {
+			time.Sleep(time.Millisecond)
+		}
+	})
+
+	t.Run("fails_fast_when_unreachable", func(t *testing.T) {
+		errA := errors.New("a")
+		release := make(chan struct{})
+		defer close(release)
+
+		_, err := Quorum(t.Context(), addresses, 3, func(ctx context.Context, address string) (string, error) {
+			if address <= "3" {
+				return "", errA
+			}
+			// 2 оставшиеся не помогут набрать 3, ждать их не нужно
+			<-release
+			return address, nil
+		}, nil)
+
+		if !errors.Is(err, ErrNoQuorum) || !errors.Is(err, errA) {
+			t.Errorf("got %v, want ErrNoQuorum and replica errors", err)
+		}
+	})
+
+	t.Run("invalid_n", func(t *testing.T) {
+		_, err := Quorum(t.Context(), addresses, 6, func(ctx context.Context, address string) (string, error) {
+			return address, nil
+		}, nil)
+
+		if !errors.Is(err, ErrNoQuorum) {
+			t.Errorf("got %v, want ErrNoQuorum", err)
+		}
+	})
+}
+
 func BenchmarkWay11(b *testing.B) {
 	for b.Loop() {
 		_, err := Way11(context.Background(), []string{"1", "2", "3"}, "key", resolvedGetter)
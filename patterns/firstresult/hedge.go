@@ -0,0 +1,196 @@
+package firstresult
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// HedgeFunc - одна попытка. По attempt (начиная с 0) можно выбрать адрес: addresses[attempt%len(addresses)].
+type HedgeFunc[T any] func(ctx context.Context, attempt int) (T, error)
+
+// HedgeConfig - параметры Hedge.
+type HedgeConfig struct {
+	Delay     HedgeDelay  // через сколько запускать следующую попытку, nil - только после ошибки предыдущей
+	MaxHedges int         // сколько дополнительных попыток можно запустить сверх первой
+	Clock     clock.Clock // nil - clock.Real
+}
+
+// Hedge - hedged requests ("The Tail at Scale", Google).
+//
+// В отличие от Way11/Way21, которые сразу опрашивают все адреса (и умножают нагрузку на N), Hedge запускает
+// одну попытку и добавляет следующую, только если первая отвечает дольше Delay. Обычно Delay берут равным p95
+// latency: тогда лишние запросы идут примерно в 5% случаев, а хвост latency резко сокращается.
+//
+// Требования:
+//   - попыток не больше 1 + MaxHedges
+//   - если попытка вернула ошибку, следующая запускается сразу, не дожидаясь Delay
+//   - первый успешный результат побеждает, остальные попытки отменяются
+//   - если все попытки неудачны, возвращается errors.Join ошибок всех попыток
+func Hedge[T any](ctx context.Context, fn HedgeFunc[T], config HedgeConfig) (T, error) {
+	if config.MaxHedges < 0 {
+		panic("max hedges must be greater or equal than 0, pass 0 if you want to disable hedging")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	clk := clock.OrReal(config.Clock)
+
+	type result struct {
+		val     T
+		err     error
+		attempt int
+		latency time.Duration
+	}
+
+	// буфер на все попытки, чтобы проигравшие не блокировались на записи
+	resCh := make(chan result, config.MaxHedges+1)
+
+	var launched int
+	launch := func() {
+		attempt := launched
+		launched++
+
+		start := clk.Now()
+		go func() {
+			val, err := fn(ctx, attempt)
+			resCh <- result{val, err, attempt, clk.Since(start)}
+		}()
+	}
+
+	var timer clock.Timer
+	var timerC <-chan time.Time
+
+	// schedule перезапускает таймер следующей попытки, если она еще возможна.
+	schedule := func() {
+		if timer != nil {
+			timer.Stop()
+		}
+
+		timer, timerC = nil, nil
+		if config.Delay != nil && launched <= config.MaxHedges {
+			timer = clk.NewTimer(config.Delay.Delay())
+			timerC = timer.C()
+		}
+	}
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	launch()
+	schedule()
+
+	var zero T
+	var errs []error
+
+	for completed := 0; completed < launched; {
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case <-timerC:
+			launch()
+			schedule()
+		case res := <-resCh:
+			completed++
+
+			if res.err == nil {
+				if o, ok := config.Delay.(LatencyObserver); ok {
+					o.Observe(res.latency)
+				}
+				return res.val, nil
+			}
+
+			errs = append(errs, fmt.Errorf("attempt %d: %w", res.attempt, res.err))
+
+			if launched <= config.MaxHedges {
+				launch()
+				schedule()
+			}
+		}
+	}
+
+	return zero, errors.Join(errs...)
+}
+
+// HedgeDelay - через сколько после предыдущей попытки запускать следующую.
+type HedgeDelay interface {
+	Delay() time.Duration
+}
+
+// LatencyObserver - HedgeDelay, которому Hedge сообщает latency успешных попыток.
+type LatencyObserver interface {
+	Observe(latency time.Duration)
+}
+
+// FixedDelay - постоянная задержка.
+type FixedDelay time.Duration
+
+func (d FixedDelay) Delay() time.Duration {
+	return time.Duration(d)
+}
+
+// PercentileDelay - задержка, равная перцентилю latency последних успешных попыток.
+// Пока наблюдений нет, используется fallback.
+type PercentileDelay struct {
+	percentile float64
+	fallback   time.Duration
+	samples    []time.Duration // кольцевой буфер
+	next       int
+	full       bool
+	mu         sync.Mutex
+}
+
+// NewPercentileDelay создает задержку по перцентилю percentile (0, 1] среди последних window наблюдений.
+func NewPercentileDelay(percentile float64, window int, fallback time.Duration) *PercentileDelay {
+	if percentile <= 0 || percentile > 1 {
+		panic("percentile must be in (0, 1]")
+	}
+	if window <= 0 {
+		panic("window must be greater than 0")
+	}
+
+	return &PercentileDelay{
+		percentile: percentile,
+		fallback:   fallback,
+		samples:    make([]time.Duration, window),
+	}
+}
+
+func (p *PercentileDelay) Observe(latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.samples[p.next] = latency
+	p.next = (p.next + 1) % len(p.samples)
+	if p.next == 0 {
+		p.full = true
+	}
+}
+
+func (p *PercentileDelay) Delay() time.Duration {
+	p.mu.Lock()
+	n := p.next
+	if p.full {
+		n = len(p.samples)
+	}
+	sorted := slices.Clone(p.samples[:n])
+	p.mu.Unlock()
+
+	if len(sorted) == 0 {
+		return p.fallback
+	}
+
+	// nearest-rank
+	slices.Sort(sorted)
+	rank := int(math.Ceil(p.percentile*float64(len(sorted)))) - 1
+
+	return sorted[max(rank, 0)]
+}
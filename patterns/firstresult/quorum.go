@@ -0,0 +1,104 @@
+package firstresult
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNoQuorum - кворум недостижим: неудачных ответов уже больше, чем len(addresses) - n.
+var ErrNoQuorum = errors.New("quorum is not reachable")
+
+// QuorumFunc - запрос к одной реплике.
+type QuorumFunc[T any] func(ctx context.Context, address string) (T, error)
+
+// Resolver выбирает итоговое значение из ответов кворума (например, самое свежее).
+type Resolver[T any] func(values []T) T
+
+// Quorum опрашивает все адреса и ждет n успешных ответов (first-N-of-M).
+//
+// Используется для чтения из реплицированных хранилищ (Cassandra, Dynamo): при n = M/2 + 1 и записи с тем же кворумом
+// хотя бы одна из прочитанных реплик содержит последнюю запись.
+//
+// Требования:
+//   - как только получено n успешных ответов, остальные запросы отменяются, а ответы передаются в resolve
+//   - если resolve nil, то возвращается первый из полученных ответов
+//   - как только кворум стал недостижим, возвращается ErrNoQuorum вместе с ошибками реплик, не дожидаясь остальных
+func Quorum[T any](ctx context.Context, addresses []string, n int, fn QuorumFunc[T], resolve Resolver[T]) (T, error) {
+	var zero T
+
+	if n <= 0 || n > len(addresses) {
+		return zero, fmt.Errorf("quorum %d of %d: %w", n, len(addresses), ErrNoQuorum)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		address string
+		val     T
+		err     error
+	}
+
+	// буфер на все ответы: после решения никто не читает, а goroutines не должны зависнуть
+	resCh := make(chan result, len(addresses))
+
+	for _, addr := range addresses {
+		go func() {
+			val, err := fn(ctx, addr)
+			resCh <- result{addr, val, err}
+		}()
+	}
+
+	values := make([]T, 0, n)
+	var errs []error
+	allowedFailures := len(addresses) - n
+
+	for range addresses {
+		var res result
+
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case res = <-resCh:
+		}
+
+		if res.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", res.address, res.err))
+			if len(errs) > allowedFailures {
+				return zero, errors.Join(append([]error{ErrNoQuorum}, errs...)...)
+			}
+			continue
+		}
+
+		values = append(values, res.val)
+		if len(values) == n {
+			// defer cancel отменит оставшиеся запросы
+			if resolve == nil {
+				return values[0], nil
+			}
+			return resolve(values), nil
+		}
+	}
+
+	// недостижимо: либо набрали n ответов, либо ошибок стало больше допустимого
+	return zero, ErrNoQuorum
+}
+
+// Versioned - значение с версией (timestamp записи, номер ревизии).
+type Versioned[V any] struct {
+	Value   V
+	Version int64
+}
+
+// LastWriteWins - Resolver, выбирающий значение с наибольшей версией.
+// При равных версиях побеждает первое из полученных.
+func LastWriteWins[V any](values []Versioned[V]) Versioned[V] {
+	latest := values[0]
+	for _, v := range values[1:] {
+		if v.Version > latest.Version {
+			latest = v
+		}
+	}
+	return latest
+}
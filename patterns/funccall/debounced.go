@@ -1,6 +1,7 @@
 package funccall
 
 import (
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
 	"sync"
 	"time"
 )
@@ -18,10 +19,12 @@ import (
 //   - работа в concurrent-среде
 //
 // @idiomatic calling defer mu.Unlock() для защиты всей функции
-func Debounced(f func(), delay time.Duration) func() {
+func Debounced(f func(), delay time.Duration, opts ...clock.Option) func() {
+	clk := clock.FromOptions(opts)
+
 	// Этот mutex и timer получается общий для всех goroutines использующих один returned-функцию.
 	var mu sync.Mutex
-	var timer *time.Timer // nillable потому что надо отличать первое использование
+	var timer clock.Timer // nillable потому что надо отличать первое использование
 
 	return func() {
 		// Mutex на всю функцию.
@@ -38,7 +41,7 @@ func Debounced(f func(), delay time.Duration) func() {
 		// Получаем экземпляр timer, через вызов Stop которого можно отменить выполнение.
 		// Здесь при использовании любого решения нужно что-то вроде timer, потому что нужно именно отложить вызов на определенное время.
 		// Мы здесь не можем просто ждать следующего вызова, как в случае с throttle.
-		timer = time.AfterFunc(delay, f)
+		timer = clk.AfterFunc(delay, f)
 
 		// Можно повторно использовать timer через Reset, но у него есть свои тонкости:
 		// 1) Reset должен вызываться только на таймере, который остановлен и не имеет ожидающего срабатывания.
@@ -1,6 +1,7 @@
 package funccall
 
 import (
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -106,4 +107,32 @@ func TestDebounce(t *testing.T) {
 			t.Errorf("got %d, want %d", cnt, 1)
 		}
 	})
+
+	t.Run("fake_clock", func(t *testing.T) {
+		c := clock.NewFake()
+
+		var cnt int32
+		f := func() {
+			atomic.AddInt32(&cnt, 1)
+		}
+
+		debounced := Debounced(f, 50*time.Millisecond, clock.With(c))
+
+		debounced()
+		c.Advance(40 * time.Millisecond)
+
+		// откладывает вызов еще на 50ms
+		debounced()
+		c.Advance(40 * time.Millisecond)
+
+		if atomic.LoadInt32(&cnt) != 0 {
+			t.Errorf("got %d, want %d", cnt, 0)
+		}
+
+		c.Advance(10 * time.Millisecond)
+
+		if atomic.LoadInt32(&cnt) != 1 {
+			t.Errorf("got %d, want %d", cnt, 1)
+		}
+	})
 }
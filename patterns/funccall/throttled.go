@@ -1,6 +1,7 @@
 package funccall
 
 import (
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
 	"sync"
 	"time"
 )
@@ -15,7 +16,9 @@ import (
 //   - работа в concurrent-среде
 //
 // @idiomatic go f()
-func Throttled(f func(), interval time.Duration) func() {
+func Throttled(f func(), interval time.Duration, opts ...clock.Option) func() {
+	clk := clock.FromOptions(opts)
+
 	var mu sync.Mutex
 
 	// благодаря zero-time выполняется требование Leading Edge
@@ -26,8 +29,8 @@ func Throttled(f func(), interval time.Duration) func() {
 		defer mu.Unlock()
 
 		// В случае первого вызова у нас в lastCall хранится zero value 0001-01-01 00:00:00 +0000, поэтому сработает сразу.
-		if time.Since(lastCall) > interval {
-			lastCall = time.Now()
+		if clk.Since(lastCall) > interval {
+			lastCall = clk.Now()
 			go f()
 		}
 	}
@@ -1,6 +1,7 @@
 package funccall
 
 import (
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -88,4 +89,36 @@ func TestThrottle(t *testing.T) {
 			t.Errorf("got %d, want %d", cnt, 1)
 		}
 	})
+
+	t.Run("fake_clock", func(t *testing.T) {
+		c := clock.NewFake()
+
+		calls := make(chan struct{}, 10)
+		f := func() {
+			calls <- struct{}{}
+		}
+
+		throttled := Throttled(f, 50*time.Millisecond, clock.With(c))
+
+		throttled()
+		c.Advance(40 * time.Millisecond)
+		throttled() // ignored
+		c.Advance(20 * time.Millisecond)
+		throttled()
+
+		// f вызывается в отдельной goroutine, поэтому ждем, но не спим
+		for i := 0; i < 2; i++ {
+			select {
+			case <-calls:
+			case <-time.After(time.Second):
+				t.Fatalf("got %d calls, want %d", i, 2)
+			}
+		}
+
+		select {
+		case <-calls:
+			t.Errorf("got extra call")
+		default:
+		}
+	})
 }
@@ -0,0 +1,282 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ErrorPolicy - что делать, если функция стадии вернула ошибку.
+type ErrorPolicy int
+
+const (
+	// FailFast - остановить весь pipeline: отменить все стадии, Run вернет первую ошибку (как errgroup).
+	FailFast ErrorPolicy = iota
+	// Skip - пропустить значение и сообщить о нем в обработчик WithSkipHandler.
+	Skip
+	// DeadLetter - отправить значение вместе с ошибкой в канал WithDeadLetter и продолжить.
+	DeadLetter
+)
+
+// TryMapFunc - как SimpleMapFunc, но может вернуть ошибку.
+type TryMapFunc[T, R any] func(context.Context, T) (R, error)
+
+// StageError - ошибка обработки значения Item в стадии Stage.
+type StageError struct {
+	Stage string
+	Item  any
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("stage %s: %v", e.Stage, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// Pipeline объединяет стадии, которые могут вернуть ошибку, в одну группу.
+//
+// Стадии создаются функциями TryMap, TryFilter, TrySink (и Go для своих источников) и сразу запускаются,
+// а Run ждет, пока все они не завершатся. Если стадия с FailFast получила ошибку, то контекст всех стадий
+// отменяется, и они закрывают свои каналы - так pipeline не зависнет на записи в канал, который больше никто не читает.
+//
+// Пример:
+//
+//	p := NewPipeline(WithDeadLetter(dlq))
+//	parsed := TryMap(p, "parse", parse, DeadLetter)(ctx, lines)
+//	TrySink(p, "save", save, FailFast)(ctx, parsed)
+//	err := p.Run(ctx)
+type Pipeline struct {
+	ctx    context.Context // отменяется при FailFast-ошибке и при отмене ctx в Run
+	cancel context.CancelFunc
+
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+
+	onSkip      func(*StageError)
+	deadLetters chan<- *StageError
+}
+
+func NewPipeline(opts ...PipelineOptionFunc) *Pipeline {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	p := &Pipeline{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Go запускает произвольную стадию (например, источник) в группе pipeline.
+// Ошибка fn действует как FailFast.
+func (p *Pipeline) Go(ctx context.Context, fn func(ctx context.Context) error) {
+	ctx, cancel := p.stageContext(ctx)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer cancel()
+
+		if err := fn(ctx); err != nil {
+			p.fail(err)
+		}
+	}()
+}
+
+// Run ждет, пока все стадии завершатся, и возвращает первую FailFast-ошибку.
+// Отмена ctx отменяет все стадии, тогда Run возвращает ctx.Err().
+func (p *Pipeline) Run(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, p.cancel)
+	defer stop()
+
+	p.wg.Wait()
+	p.cancel()
+
+	if p.err != nil {
+		return p.err
+	}
+	return ctx.Err()
+}
+
+// fail запоминает первую ошибку и останавливает pipeline.
+func (p *Pipeline) fail(err error) {
+	p.errOnce.Do(func() {
+		p.err = err
+		p.cancel()
+	})
+}
+
+// handle применяет policy к ошибке, false - стадию надо остановить.
+func (p *Pipeline) handle(ctx context.Context, policy ErrorPolicy, stageErr *StageError) bool {
+	switch policy {
+	case Skip:
+		if p.onSkip != nil {
+			p.onSkip(stageErr)
+		}
+		return true
+	case DeadLetter:
+		return send(ctx, p.deadLetters, stageErr)
+	default:
+		p.fail(stageErr)
+		return false
+	}
+}
+
+// stageContext - ctx стадии, который отменяется и при отмене ctx, и при остановке pipeline.
+func (p *Pipeline) stageContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(p.ctx, cancel)
+
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+func (p *Pipeline) checkPolicy(policy ErrorPolicy) {
+	if policy == DeadLetter && p.deadLetters == nil {
+		panic("dead letter policy requires WithDeadLetter")
+	}
+}
+
+// TryMap - Map, функция которой может вернуть ошибку.
+func TryMap[T, R any](p *Pipeline, name string, f TryMapFunc[T, R], policy ErrorPolicy, opts ...StageOptionFunc) PipelinedChannel[T, R] {
+	p.checkPolicy(policy)
+
+	return func(ctx context.Context, inputCh <-chan T) <-chan R {
+		outputCh := make(chan R)
+		pr := newProbe(opts, inputCh, (<-chan R)(outputCh))
+
+		p.Go(ctx, func(ctx context.Context) error {
+			defer close(outputCh)
+
+			for {
+				val, ok := recv(ctx, pr, inputCh)
+				if !ok {
+					return nil
+				}
+
+				start := pr.now()
+				res, err := f(ctx, val)
+				pr.processed(start)
+
+				if err != nil {
+					if !p.handle(ctx, policy, &StageError{Stage: name, Item: val, Err: err}) {
+						return nil
+					}
+					continue
+				}
+
+				if !emit(ctx, pr, outputCh, res) {
+					return nil
+				}
+			}
+		})
+
+		return outputCh
+	}
+}
+
+// TryFilter - Filter, функция которой может вернуть ошибку.
+func TryFilter[T any](p *Pipeline, name string, keep func(context.Context, T) (bool, error), policy ErrorPolicy, opts ...StageOptionFunc) PipelinedChannel[T, T] {
+	return TryFlatMap(p, name, func(ctx context.Context, val T) ([]T, error) {
+		ok, err := keep(ctx, val)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return []T{val}, nil
+	}, policy, opts...)
+}
+
+// TryFlatMap - FlatMap, функция которой может вернуть ошибку.
+func TryFlatMap[T, R any](p *Pipeline, name string, f func(context.Context, T) ([]R, error), policy ErrorPolicy, opts ...StageOptionFunc) PipelinedChannel[T, R] {
+	p.checkPolicy(policy)
+
+	return func(ctx context.Context, inputCh <-chan T) <-chan R {
+		outputCh := make(chan R)
+		pr := newProbe(opts, inputCh, (<-chan R)(outputCh))
+
+		p.Go(ctx, func(ctx context.Context) error {
+			defer close(outputCh)
+
+			for {
+				val, ok := recv(ctx, pr, inputCh)
+				if !ok {
+					return nil
+				}
+
+				start := pr.now()
+				res, err := f(ctx, val)
+				pr.processed(start)
+
+				if err != nil {
+					if !p.handle(ctx, policy, &StageError{Stage: name, Item: val, Err: err}) {
+						return nil
+					}
+					continue
+				}
+
+				for _, r := range res {
+					if !emit(ctx, pr, outputCh, r) {
+						return nil
+					}
+				}
+			}
+		})
+
+		return outputCh
+	}
+}
+
+// TrySink - последняя стадия: вызывает f для каждого значения до закрытия input.
+// Возвращает функцию, которую нужно вызвать с input-каналом, результат ждать через Run.
+func TrySink[T any](p *Pipeline, name string, f func(context.Context, T) error, policy ErrorPolicy, opts ...StageOptionFunc) func(context.Context, <-chan T) {
+	p.checkPolicy(policy)
+
+	return func(ctx context.Context, inputCh <-chan T) {
+		pr := newProbe[T, struct{}](opts, inputCh)
+
+		p.Go(ctx, func(ctx context.Context) error {
+			for {
+				val, ok := recv(ctx, pr, inputCh)
+				if !ok {
+					return nil
+				}
+
+				start := pr.now()
+				err := f(ctx, val)
+				pr.processed(start)
+
+				if err != nil {
+					if !p.handle(ctx, policy, &StageError{Stage: name, Item: val, Err: err}) {
+						return nil
+					}
+				}
+			}
+		})
+	}
+}
+
+type PipelineOptionFunc func(*Pipeline)
+
+// WithSkipHandler задает обработчик значений, пропущенных стадиями с политикой Skip (например, логирование).
+func WithSkipHandler(val func(*StageError)) PipelineOptionFunc {
+	return func(p *Pipeline) {
+		p.onSkip = val
+	}
+}
+
+// WithDeadLetter задает канал для стадий с политикой DeadLetter. Pipeline его не закрывает.
+// Если канал никто не читает, то стадии блокируются на записи в него (backpressure).
+func WithDeadLetter(val chan<- *StageError) PipelineOptionFunc {
+	return func(p *Pipeline) {
+		p.deadLetters = val
+	}
+}
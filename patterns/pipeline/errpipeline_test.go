@@ -0,0 +1,139 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPipelineErrors(t *testing.T) {
+	parse := func(_ context.Context, s string) (int, error) {
+		return strconv.Atoi(s)
+	}
+
+	// source - источник внутри группы pipeline
+	source := func(p *Pipeline, ctx context.Context, values ...string) <-chan string {
+		outputCh := make(chan string)
+		p.Go(ctx, func(ctx context.Context) error {
+			defer close(outputCh)
+			for _, v := range values {
+				if !send(ctx, outputCh, v) {
+					return nil
+				}
+			}
+			return nil
+		})
+		return outputCh
+	}
+
+	t.Run("fail_fast", func(t *testing.T) {
+		ctx := t.Context()
+		p := NewPipeline()
+
+		// бесконечный источник: без отмены pipeline Run бы не завершился
+		naturals := make(chan string)
+		p.Go(ctx, func(ctx context.Context) error {
+			defer close(naturals)
+			for i := 0; ; i++ {
+				v := strconv.Itoa(i)
+				if i == 5 {
+					v = "bad"
+				}
+				if !send(ctx, naturals, v) {
+					return nil
+				}
+			}
+		})
+
+		parsed := TryMap(p, "parse", parse, FailFast)(ctx, naturals)
+		TrySink(p, "sink", func(context.Context, int) error { return nil }, FailFast)(ctx, parsed)
+
+		err := p.Run(ctx)
+
+		var stageErr *StageError
+		if !errors.As(err, &stageErr) || stageErr.Stage != "parse" || stageErr.Item != "bad" {
+			t.Fatalf("got %v, want parse error for \"bad\"", err)
+		}
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		ctx := t.Context()
+
+		var skipped []any
+		p := NewPipeline(WithSkipHandler(func(err *StageError) {
+			skipped = append(skipped, err.Item)
+		}))
+
+		parsed := TryMap(p, "parse", parse, Skip)(ctx, source(p, ctx, "1", "x", "2", "y"))
+
+		var got []int
+		TrySink(p, "collect", func(_ context.Context, v int) error {
+			got = append(got, v)
+			return nil
+		}, FailFast)(ctx, parsed)
+
+		if err := p.Run(ctx); err != nil {
+			t.Fatalf("got error %v", err)
+		}
+
+		if !slices.Equal(got, []int{1, 2}) || len(skipped) != 2 {
+			t.Errorf("got %v and skipped %v", got, skipped)
+		}
+	})
+
+	t.Run("dead_letter", func(t *testing.T) {
+		ctx := t.Context()
+
+		dlq := make(chan *StageError, 10)
+		p := NewPipeline(WithDeadLetter(dlq))
+
+		even := TryFilter(p, "even", func(_ context.Context, v int) (bool, error) {
+			if v < 0 {
+				return false, fmt.Errorf("negative %d", v)
+			}
+			return v%2 == 0, nil
+		}, DeadLetter)
+
+		parsed := TryMap(p, "parse", parse, DeadLetter)(ctx, source(p, ctx, "2", "x", "-4", "3", "6"))
+
+		var got []int
+		TrySink(p, "collect", func(_ context.Context, v int) error {
+			got = append(got, v)
+			return nil
+		}, FailFast)(ctx, even(ctx, parsed))
+
+		if err := p.Run(ctx); err != nil {
+			t.Fatalf("got error %v", err)
+		}
+		close(dlq)
+
+		var stages []string
+		for e := range dlq {
+			stages = append(stages, fmt.Sprintf("%s:%v", e.Stage, e.Item))
+		}
+		slices.Sort(stages)
+
+		if !slices.Equal(got, []int{2, 6}) || !slices.Equal(stages, []string{"even:-4", "parse:x"}) {
+			t.Errorf("got %v and dead letters %v", got, stages)
+		}
+	})
+
+	t.Run("run_cancel", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+		defer cancel()
+
+		p := NewPipeline()
+		p.Go(ctx, func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+
+		if err := p.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got %v, want deadline exceeded", err)
+		}
+	})
+}
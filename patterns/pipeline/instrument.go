@@ -0,0 +1,244 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Monitor собирает метрики стадий, подключенных через WithMonitor.
+//
+// Зачем:
+// Когда pipeline тормозит, по общей скорости не понять, какая стадия узкое место. Помогает время ожидания:
+//   - стадия долго ждет на receive - ее кормят медленно, узкое место выше по течению
+//   - стадия долго ждет на send - ее не успевают читать (backpressure, см. explore/backpressure), узкое место ниже
+//   - у узкого места большое время обработки и почти нет ожиданий
+type Monitor struct {
+	mu     sync.Mutex
+	stages []*stageStats
+
+	// nil, если prometheus не нужен
+	items      *prometheus.CounterVec
+	processing *prometheus.HistogramVec
+	waiting    *prometheus.HistogramVec
+}
+
+// NewMonitor создает Monitor, метрики регистрируются в reg (nil - только Describe, без prometheus).
+func NewMonitor(reg prometheus.Registerer) *Monitor {
+	m := &Monitor{}
+	if reg == nil {
+		return m
+	}
+
+	m.items = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "pipeline_stage_items_total",
+		Help: "Количество значений, прочитанных (in) и отправленных (out) стадией",
+	}, []string{"stage", "direction"})
+	m.processing = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "pipeline_stage_processing_seconds",
+		Help:    "Время обработки одного значения",
+		Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10), // 100µs..~26s
+	}, []string{"stage"})
+	m.waiting = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "pipeline_stage_blocked_seconds",
+		Help:    "Время ожидания на receive (медленный источник) и send (backpressure)",
+		Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10),
+	}, []string{"stage", "op"})
+
+	reg.MustRegister(m.items, m.processing, m.waiting)
+
+	return m
+}
+
+// Describe возвращает текущее состояние стадий. Стадии выводятся в порядке запуска,
+// для каждой указаны стадии, из которых она читает (определяется по общему каналу).
+func (m *Monitor) Describe() string {
+	m.mu.Lock()
+	stages := append([]*stageStats(nil), m.stages...)
+	m.mu.Unlock()
+
+	var b strings.Builder
+	for _, s := range stages {
+		var from []string
+		for _, other := range stages {
+			if slices.Contains(other.outputs, s.input) {
+				from = append(from, other.name)
+			}
+		}
+
+		source := "external"
+		if len(from) > 0 {
+			source = strings.Join(from, ", ")
+		}
+
+		in, out := s.in.Load(), s.out.Load()
+		var avg time.Duration
+		if in > 0 {
+			avg = time.Duration(s.processing.Load() / in)
+		}
+
+		fmt.Fprintf(&b, "%s <- %s: in=%d out=%d avg_processing=%v blocked_receive=%v blocked_send=%v\n",
+			s.name, source, in, out, avg,
+			time.Duration(s.blockedRecv.Load()), time.Duration(s.blockedSend.Load()))
+	}
+
+	return b.String()
+}
+
+func (m *Monitor) register(name string, input any, outputs []any) *stageStats {
+	s := &stageStats{name: name, input: input, outputs: outputs}
+
+	if m.items != nil {
+		s.inCounter = m.items.WithLabelValues(name, "in")
+		s.outCounter = m.items.WithLabelValues(name, "out")
+		s.processingHist = m.processing.WithLabelValues(name)
+		s.recvHist = m.waiting.WithLabelValues(name, "receive")
+		s.sendHist = m.waiting.WithLabelValues(name, "send")
+	}
+
+	m.mu.Lock()
+	m.stages = append(m.stages, s)
+	m.mu.Unlock()
+
+	return s
+}
+
+// stageStats - счетчики одной стадии. Время хранится в наносекундах.
+type stageStats struct {
+	name    string
+	input   any   // <-chan T, по нему строится топология
+	outputs []any // у Tee их несколько, у Sink - ни одного
+
+	in          atomic.Int64
+	out         atomic.Int64
+	processing  atomic.Int64
+	blockedRecv atomic.Int64
+	blockedSend atomic.Int64
+
+	inCounter      prometheus.Counter
+	outCounter     prometheus.Counter
+	processingHist prometheus.Observer
+	recvHist       prometheus.Observer
+	sendHist       prometheus.Observer
+}
+
+type stageOptions struct {
+	monitor *Monitor
+	name    string
+}
+
+// StageOptionFunc - параметры стадии.
+type StageOptionFunc func(*stageOptions)
+
+// WithMonitor подключает стадию к Monitor под именем name.
+func WithMonitor(monitor *Monitor, name string) StageOptionFunc {
+	return func(o *stageOptions) {
+		o.monitor = monitor
+		o.name = name
+	}
+}
+
+// probe - измерения внутри стадии. nil - стадия без мониторинга, все методы ничего не делают.
+type probe struct {
+	stats *stageStats
+}
+
+func newProbe[T, R any](opts []StageOptionFunc, inputCh <-chan T, outputChs ...<-chan R) *probe {
+	var o stageOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.monitor == nil {
+		return nil
+	}
+
+	outputs := make([]any, len(outputChs))
+	for i, ch := range outputChs {
+		outputs[i] = ch
+	}
+
+	return &probe{stats: o.monitor.register(o.name, inputCh, outputs)}
+}
+
+// processed фиксирует время обработки одного значения, начатой в start.
+func (p *probe) processed(start time.Time) {
+	if p == nil {
+		return
+	}
+
+	d := time.Since(start)
+	p.stats.processing.Add(int64(d))
+	if p.stats.processingHist != nil {
+		p.stats.processingHist.Observe(d.Seconds())
+	}
+}
+
+func (p *probe) received(start time.Time) {
+	if p == nil {
+		return
+	}
+
+	d := time.Since(start)
+	p.stats.in.Add(1)
+	p.stats.blockedRecv.Add(int64(d))
+	if p.stats.inCounter != nil {
+		p.stats.inCounter.Inc()
+		p.stats.recvHist.Observe(d.Seconds())
+	}
+}
+
+func (p *probe) sent(start time.Time) {
+	if p == nil {
+		return
+	}
+
+	d := time.Since(start)
+	p.stats.out.Add(1)
+	p.stats.blockedSend.Add(int64(d))
+	if p.stats.outCounter != nil {
+		p.stats.outCounter.Inc()
+		p.stats.sendHist.Observe(d.Seconds())
+	}
+}
+
+// now - время начала измерения, без мониторинга не вызываем time.Now лишний раз.
+func (p *probe) now() time.Time {
+	if p == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// recv читает из ch с учетом ожидания, false - канал закрыт или ctx отменен.
+func recv[T any](ctx context.Context, p *probe, ch <-chan T) (T, bool) {
+	start := p.now()
+
+	select {
+	case <-ctx.Done():
+		var zero T
+		return zero, false
+	case val, ok := <-ch:
+		if ok {
+			p.received(start)
+		}
+		return val, ok
+	}
+}
+
+// emit - send с учетом ожидания.
+func emit[T any](ctx context.Context, p *probe, ch chan<- T, val T) bool {
+	start := p.now()
+
+	if !send(ctx, ch, val) {
+		return false
+	}
+
+	p.sent(start)
+	return true
+}
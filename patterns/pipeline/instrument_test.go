@@ -0,0 +1,130 @@
+package pipeline
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMonitor(t *testing.T) {
+	t.Run("items_and_topology", func(t *testing.T) {
+		m := NewMonitor(prometheus.NewRegistry())
+		ctx := t.Context()
+
+		double := Map(func(_ context.Context, v int) int { return v * 2 }, WithMonitor(m, "double"))
+		small := Filter(func(v int) bool { return v < 10 }, WithMonitor(m, "small"))
+
+		var sum int
+		err := Sink(ctx, Chain(double, small)(ctx, Source(ctx, 1, 2, 3, 4, 5, 6)), func(_ context.Context, v int) {
+			sum += v
+		}, WithMonitor(m, "sum"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if sum != 20 {
+			t.Errorf("got %v, want 20", sum)
+		}
+
+		if got := testutil.ToFloat64(m.items.WithLabelValues("small", "in")); got != 6 {
+			t.Errorf("got %v items in, want 6", got)
+		}
+		if got := testutil.ToFloat64(m.items.WithLabelValues("small", "out")); got != 4 {
+			t.Errorf("got %v items out, want 4", got)
+		}
+		if got := testutil.CollectAndCount(m.processing); got != 3 {
+			t.Errorf("got %v processing histograms, want 3", got)
+		}
+
+		lines := strings.Split(strings.TrimSpace(m.Describe()), "\n")
+		want := []string{"double <- external:", "small <- double:", "sum <- small:"}
+		if len(lines) != len(want) {
+			t.Fatalf("got %q, want %v lines", lines, len(want))
+		}
+		for i, prefix := range want {
+			if !strings.HasPrefix(lines[i], prefix) {
+				t.Errorf("got %q, want prefix %q", lines[i], prefix)
+			}
+		}
+	})
+
+	t.Run("backpressure", func(t *testing.T) {
+		m := NewMonitor(nil)
+		ctx := t.Context()
+
+		// быстрая стадия перед медленным потребителем ждет на send, а не на receive
+		fast := Map(func(_ context.Context, v int) int { return v }, WithMonitor(m, "fast"))
+		err := Sink(ctx, fast(ctx, Source(ctx, 1, 2, 3, 4, 5)), func(context.Context, int) {
+			time.Sleep(10 * time.Millisecond)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		stats := m.stages[0]
+		if stats.in.Load() != 5 || stats.out.Load() != 5 {
+			t.Errorf("got in=%v out=%v, want 5 and 5", stats.in.Load(), stats.out.Load())
+		}
+
+		blockedSend := time.Duration(stats.blockedSend.Load())
+		blockedRecv := time.Duration(stats.blockedRecv.Load())
+		if blockedSend < 30*time.Millisecond || blockedSend <= blockedRecv {
+			t.Errorf("got blocked send %v and receive %v, want send > 30ms and > receive", blockedSend, blockedRecv)
+		}
+	})
+
+	t.Run("tee_and_window", func(t *testing.T) {
+		m := NewMonitor(nil)
+		ctx := t.Context()
+
+		outs := Tee(ctx, Source(ctx, 1, 2, 3), 2, WithMonitor(m, "tee"))
+		sum := Window(WindowConfig[int]{Spec: Tumbling(time.Hour)}, Aggregator[int, int]{
+			Init: func() int { return 0 },
+			Add:  func(acc int, v int) int { return acc + v },
+		}, WithMonitor(m, "window"))(ctx, outs[0])
+		copied := Map(func(_ context.Context, v int) int { return v }, WithMonitor(m, "copy"))(ctx, outs[1])
+
+		// Tee ждет обоих потребителей, поэтому читаем их параллельно
+		done := make(chan []int)
+		go func() {
+			done <- collect(copied)
+		}()
+		windows := collect(sum)
+		<-done
+
+		if len(windows) != 1 || windows[0].Value != 6 {
+			t.Errorf("got %v, want one window with 6", windows)
+		}
+
+		tee, window := m.stages[0], m.stages[1]
+		if tee.in.Load() != 3 || tee.out.Load() != 6 {
+			t.Errorf("got tee in=%v out=%v, want 3 and 6", tee.in.Load(), tee.out.Load())
+		}
+		if window.in.Load() != 3 || window.out.Load() != 1 {
+			t.Errorf("got window in=%v out=%v, want 3 and 1", window.in.Load(), window.out.Load())
+		}
+
+		lines := strings.Split(strings.TrimSpace(m.Describe()), "\n")
+		want := []string{"tee <- external:", "window <- tee:", "copy <- tee:"}
+		if len(lines) != len(want) {
+			t.Fatalf("got %q, want %v lines", lines, len(want))
+		}
+		for i, prefix := range want {
+			if !strings.HasPrefix(lines[i], prefix) {
+				t.Errorf("got %q, want prefix %q", lines[i], prefix)
+			}
+		}
+	})
+
+	t.Run("without_monitor", func(t *testing.T) {
+		ctx := t.Context()
+
+		got := collect(Map(func(_ context.Context, v int) int { return v + 1 })(ctx, Source(ctx, 1, 2)))
+		if len(got) != 2 || got[0] != 2 || got[1] != 3 {
+			t.Errorf("got %v, want [2 3]", got)
+		}
+	})
+}
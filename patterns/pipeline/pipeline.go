@@ -4,25 +4,26 @@ import "context"
 
 // ExampleMappedBuilder
 // @idiomatic Producer сам закрывает свой канал
-func ExampleMappedBuilder[T, R any](ctx context.Context, inputCh <-chan T, f SimpleMapFunc[T, R]) PipelinedChannel[T, R] {
-	return func(context.Context, <-chan T) <-chan R {
+func ExampleMappedBuilder[T, R any](f SimpleMapFunc[T, R]) PipelinedChannel[T, R] {
+	return func(ctx context.Context, inputCh <-chan T) <-chan R {
 		outputCh := make(chan R)
 
 		go func() {
 			defer close(outputCh)
 
 			for val := range inputCh {
-				// Такой проверки недостаточно
+				// Такой проверки недостаточно: отмена может случиться, пока мы заблокированы на записи.
 				/*
 					if ctx.Err() != nil {
 						return
 					}
 				*/
+				// И select с default тоже не помогает: запись в default-ветке блокируется так же.
+				// Ждать нужно одновременно и отмену, и запись.
 				select {
 				case <-ctx.Done():
 					return
-				default:
-					outputCh <- f(ctx, val)
+				case outputCh <- f(ctx, val):
 				}
 			}
 		}()
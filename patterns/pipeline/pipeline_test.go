@@ -2,8 +2,11 @@ package pipeline
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 )
@@ -144,3 +147,169 @@ func TestPipeline(t *testing.T) {
 		}
 	})
 }
+
+func TestStages(t *testing.T) {
+	t.Run("chain", func(t *testing.T) {
+		ctx := t.Context()
+
+		stage := Chain4(
+			Filter(func(v int) bool { return v%2 == 1 }),
+			Map(func(_ context.Context, v int) int { return v * v }),
+			FlatMap(func(_ context.Context, v int) []string { return []string{fmt.Sprint(v), fmt.Sprint(v)} }),
+			Distinct[string](),
+		)
+
+		got := collect(stage(ctx, Source(ctx, 1, 2, 3, 4, 5)))
+		if !slices.Equal(got, []string{"1", "9", "25"}) {
+			t.Errorf("got %v", got)
+		}
+	})
+
+	t.Run("parallel_map_and_reduce", func(t *testing.T) {
+		ctx := t.Context()
+
+		stage := Chain(
+			ParallelMap(4, func(_ context.Context, v int) int { return v * v }),
+			Reduce(0, func(acc int, v int) int { return acc + v }),
+		)
+
+		got := collect(stage(ctx, Source(ctx, 1, 3, 5, 7, 9)))
+		if !slices.Equal(got, []int{165}) {
+			t.Errorf("got %v, want [165]", got)
+		}
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		ctx := t.Context()
+
+		got := collect(Batch[int](2, time.Hour)(ctx, Source(ctx, 1, 2, 3, 4, 5)))
+		if len(got) != 3 || !slices.Equal(got[2], []int{5}) {
+			t.Errorf("got %v, want [[1 2] [3 4] [5]]", got)
+		}
+	})
+
+	t.Run("batch_max_wait", func(t *testing.T) {
+		inputCh := make(chan int)
+		defer close(inputCh)
+
+		outputCh := Batch[int](10, 10*time.Millisecond)(t.Context(), inputCh)
+		inputCh <- 1
+
+		select {
+		case batch := <-outputCh:
+			if !slices.Equal(batch, []int{1}) {
+				t.Errorf("got %v, want [1]", batch)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("partial batch was not flushed")
+		}
+	})
+
+	t.Run("tee", func(t *testing.T) {
+		ctx := t.Context()
+		outs := Tee(ctx, Source(ctx, 1, 2, 3), 2)
+
+		var wg sync.WaitGroup
+		results := make([][]int, 2)
+		for i, ch := range outs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i] = collect(ch)
+			}()
+		}
+		wg.Wait()
+
+		for _, res := range results {
+			if !slices.Equal(res, []int{1, 2, 3}) {
+				t.Errorf("got %v, want [1 2 3]", res)
+			}
+		}
+	})
+
+	t.Run("take_and_cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+
+		// бесконечный источник
+		naturals := make(chan int)
+		go func() {
+			defer close(naturals)
+			for i := 0; ; i++ {
+				if !send(ctx, naturals, i) {
+					return
+				}
+			}
+		}()
+
+		got := collect(Take[int](3)(ctx, naturals))
+		if !slices.Equal(got, []int{0, 1, 2}) {
+			t.Errorf("got %v, want [0 1 2]", got)
+		}
+	})
+
+	t.Run("sink", func(t *testing.T) {
+		ctx := t.Context()
+
+		var sum int
+		err := Sink(ctx, Source(ctx, 1, 2, 3), func(_ context.Context, v int) { sum += v })
+		if err != nil || sum != 6 {
+			t.Errorf("got %v, %v, want 6, nil", sum, err)
+		}
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		if err := Sink(canceled, make(chan int), func(context.Context, int) {}); !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v, want canceled", err)
+		}
+	})
+
+	t.Run("stages_close_on_cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+
+		stages := map[string]PipelinedChannel[int, int]{
+			"map":          Map(func(_ context.Context, v int) int { return v }),
+			"filter":       Filter(func(int) bool { return true }),
+			"parallel_map": ParallelMap(3, func(_ context.Context, v int) int { return v }),
+			"distinct":     Distinct[int](),
+		}
+
+		inputCh := make(chan int, 1)
+		inputCh <- 1
+		close(inputCh)
+
+		outs := make(map[string]<-chan int)
+		for name, stage := range stages {
+			outs[name] = stage(ctx, inputCh)
+		}
+
+		// никто не читает - все висят на записи, пока не отменим
+		cancel()
+
+		for name, ch := range outs {
+			select {
+			case <-waitClosed(ch):
+			case <-time.After(time.Second):
+				t.Errorf("%s: output is not closed after cancel", name)
+			}
+		}
+	})
+}
+
+func collect[T any](ch <-chan T) []T {
+	var res []T
+	for v := range ch {
+		res = append(res, v)
+	}
+	return res
+}
+
+func waitClosed[T any](ch <-chan T) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+	return done
+}
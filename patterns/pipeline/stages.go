@@ -0,0 +1,409 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Библиотека стадий. Каждая стадия - PipelinedChannel, поэтому они соединяются друг с другом и с Chain.
+//
+// Общие правила для всех стадий:
+//   - output-канал закрывает сама стадия (после закрытия input или отмены ctx)
+//   - запись в output всегда в select с ctx.Done(), иначе после отмены goroutine навсегда зависнет на записи
+//   - чтение input тоже в select с ctx.Done(): после отмены стадия завершается, даже если источник не закрыл канал
+//   - opts - необязательные параметры стадии, например WithMonitor
+//
+// Время обработки считается от получения значения до готовности результата (без ожидания на send).
+
+// Map применяет f к каждому значению.
+func Map[T, R any](f SimpleMapFunc[T, R], opts ...StageOptionFunc) PipelinedChannel[T, R] {
+	return func(ctx context.Context, inputCh <-chan T) <-chan R {
+		outputCh := make(chan R)
+		p := newProbe(opts, inputCh, (<-chan R)(outputCh))
+
+		go func() {
+			defer close(outputCh)
+
+			for {
+				val, ok := recv(ctx, p, inputCh)
+				if !ok {
+					return
+				}
+
+				start := p.now()
+				res := f(ctx, val)
+				p.processed(start)
+
+				if !emit(ctx, p, outputCh, res) {
+					return
+				}
+			}
+		}()
+
+		return outputCh
+	}
+}
+
+// Filter пропускает только значения, для которых keep возвращает true.
+func Filter[T any](keep func(T) bool, opts ...StageOptionFunc) PipelinedChannel[T, T] {
+	return func(ctx context.Context, inputCh <-chan T) <-chan T {
+		outputCh := make(chan T)
+		p := newProbe(opts, inputCh, (<-chan T)(outputCh))
+
+		go func() {
+			defer close(outputCh)
+
+			for {
+				val, ok := recv(ctx, p, inputCh)
+				if !ok {
+					return
+				}
+
+				start := p.now()
+				passed := keep(val)
+				p.processed(start)
+
+				if !passed {
+					continue
+				}
+				if !emit(ctx, p, outputCh, val) {
+					return
+				}
+			}
+		}()
+
+		return outputCh
+	}
+}
+
+// FlatMap превращает каждое значение в 0..N значений.
+func FlatMap[T, R any](f func(context.Context, T) []R, opts ...StageOptionFunc) PipelinedChannel[T, R] {
+	return func(ctx context.Context, inputCh <-chan T) <-chan R {
+		outputCh := make(chan R)
+		p := newProbe(opts, inputCh, (<-chan R)(outputCh))
+
+		go func() {
+			defer close(outputCh)
+
+			for {
+				val, ok := recv(ctx, p, inputCh)
+				if !ok {
+					return
+				}
+
+				start := p.now()
+				results := f(ctx, val)
+				p.processed(start)
+
+				for _, res := range results {
+					if !emit(ctx, p, outputCh, res) {
+						return
+					}
+				}
+			}
+		}()
+
+		return outputCh
+	}
+}
+
+// ParallelMap применяет f в n goroutines. Порядок результатов не сохраняется
+// (если он нужен, то см. workerpool.OrderedMap).
+func ParallelMap[T, R any](n int, f SimpleMapFunc[T, R], opts ...StageOptionFunc) PipelinedChannel[T, R] {
+	if n <= 0 {
+		panic("n must be greater than 0")
+	}
+
+	return func(ctx context.Context, inputCh <-chan T) <-chan R {
+		outputCh := make(chan R)
+		p := newProbe(opts, inputCh, (<-chan R)(outputCh))
+
+		var wg sync.WaitGroup
+		wg.Add(n)
+
+		for range n {
+			go func() {
+				defer wg.Done()
+
+				for {
+					val, ok := recv(ctx, p, inputCh)
+					if !ok {
+						return
+					}
+
+					start := p.now()
+					res := f(ctx, val)
+					p.processed(start)
+
+					if !emit(ctx, p, outputCh, res) {
+						return
+					}
+				}
+			}()
+		}
+
+		// закрыть можно только после завершения всех пишущих
+		go func() {
+			wg.Wait()
+			close(outputCh)
+		}()
+
+		return outputCh
+	}
+}
+
+// Batch собирает значения в пачки по size. Неполная пачка отправляется, если с момента появления в ней первого
+// значения прошло maxWait (чтобы при слабом потоке значения не застревали), и при закрытии input.
+func Batch[T any](size int, maxWait time.Duration, opts ...StageOptionFunc) PipelinedChannel[T, []T] {
+	if size <= 0 {
+		panic("size must be greater than 0")
+	}
+
+	return func(ctx context.Context, inputCh <-chan T) <-chan []T {
+		outputCh := make(chan []T)
+		p := newProbe(opts, inputCh, (<-chan []T)(outputCh))
+
+		go func() {
+			defer close(outputCh)
+
+			var batch []T
+
+			// nil-канал в select никогда не срабатывает: таймер "выключен", пока пачка пуста
+			var timer *time.Timer
+			var timerCh <-chan time.Time
+
+			flush := func() bool {
+				if timer != nil {
+					timer.Stop()
+					timer, timerCh = nil, nil
+				}
+				if len(batch) == 0 {
+					return true
+				}
+
+				out := batch
+				batch = nil
+				return emit(ctx, p, outputCh, out)
+			}
+
+			// ожидание input считаем вручную: здесь select еще и по таймеру
+			for {
+				start := p.now()
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-timerCh:
+					if !flush() {
+						return
+					}
+				case val, ok := <-inputCh:
+					if !ok {
+						flush()
+						return
+					}
+					p.received(start)
+
+					batch = append(batch, val)
+					if len(batch) == 1 && maxWait > 0 {
+						timer = time.NewTimer(maxWait)
+						timerCh = timer.C
+					}
+
+					if len(batch) == size && !flush() {
+						return
+					}
+				}
+			}
+		}()
+
+		return outputCh
+	}
+}
+
+// Tee раздает каждое значение во все n output-каналов. Медленный потребитель тормозит остальных:
+// следующее значение читается только после того, как текущее получили все.
+// В мониторинге out считает каждую отправку, то есть n на одно значение.
+func Tee[T any](ctx context.Context, inputCh <-chan T, n int, opts ...StageOptionFunc) []<-chan T {
+	outputChs := make([]chan T, n)
+	res := make([]<-chan T, n)
+	for i := range n {
+		outputChs[i] = make(chan T)
+		res[i] = outputChs[i]
+	}
+	p := newProbe(opts, inputCh, res...)
+
+	go func() {
+		defer func() {
+			for _, ch := range outputChs {
+				close(ch)
+			}
+		}()
+
+		for {
+			val, ok := recv(ctx, p, inputCh)
+			if !ok {
+				return
+			}
+
+			for _, ch := range outputChs {
+				if !emit(ctx, p, ch, val) {
+					return
+				}
+			}
+		}
+	}()
+
+	return res
+}
+
+// Take пропускает первые n значений и закрывает output.
+// Остаток input не читается: чтобы освободить источник, отмените ctx.
+func Take[T any](n int, opts ...StageOptionFunc) PipelinedChannel[T, T] {
+	return func(ctx context.Context, inputCh <-chan T) <-chan T {
+		outputCh := make(chan T)
+		p := newProbe(opts, inputCh, (<-chan T)(outputCh))
+
+		go func() {
+			defer close(outputCh)
+
+			for i := 0; i < n; i++ {
+				// recv читает в select: после n значений источник нам уже не нужен, но до них ждем
+				val, ok := recv(ctx, p, inputCh)
+				if !ok {
+					return
+				}
+
+				if !emit(ctx, p, outputCh, val) {
+					return
+				}
+			}
+		}()
+
+		return outputCh
+	}
+}
+
+// Distinct пропускает только первое вхождение каждого значения.
+// Память растет с количеством уникальных значений.
+func Distinct[T comparable](opts ...StageOptionFunc) PipelinedChannel[T, T] {
+	return func(ctx context.Context, inputCh <-chan T) <-chan T {
+		outputCh := make(chan T)
+		p := newProbe(opts, inputCh, (<-chan T)(outputCh))
+
+		go func() {
+			defer close(outputCh)
+
+			seen := make(map[T]struct{})
+			for {
+				val, ok := recv(ctx, p, inputCh)
+				if !ok {
+					return
+				}
+
+				if _, ok := seen[val]; ok {
+					continue
+				}
+				seen[val] = struct{}{}
+
+				if !emit(ctx, p, outputCh, val) {
+					return
+				}
+			}
+		}()
+
+		return outputCh
+	}
+}
+
+// Reduce сворачивает все значения в одно и отправляет его после закрытия input.
+func Reduce[T, R any](initial R, f func(acc R, val T) R, opts ...StageOptionFunc) PipelinedChannel[T, R] {
+	return func(ctx context.Context, inputCh <-chan T) <-chan R {
+		outputCh := make(chan R)
+		p := newProbe(opts, inputCh, (<-chan R)(outputCh))
+
+		go func() {
+			defer close(outputCh)
+
+			acc := initial
+			for {
+				val, ok := recv(ctx, p, inputCh)
+				if !ok {
+					break
+				}
+
+				start := p.now()
+				acc = f(acc, val)
+				p.processed(start)
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+			emit(ctx, p, outputCh, acc)
+		}()
+
+		return outputCh
+	}
+}
+
+// Sink - последняя стадия: вызывает f для каждого значения, пока input не закроется.
+// Возвращает ctx.Err(), если ctx отменен раньше.
+func Sink[T any](ctx context.Context, inputCh <-chan T, f func(context.Context, T), opts ...StageOptionFunc) error {
+	p := newProbe[T, struct{}](opts, inputCh)
+
+	for {
+		val, ok := recv(ctx, p, inputCh)
+		if !ok {
+			return ctx.Err()
+		}
+
+		start := p.now()
+		f(ctx, val)
+		p.processed(start)
+	}
+}
+
+// Chain соединяет две стадии в одну. Типы проверяются компилятором: выход первой должен совпадать со входом второй.
+// Variadic-версию на generics не написать (у стадий разные типы), поэтому есть Chain3 и Chain4.
+func Chain[A, B, C any](first PipelinedChannel[A, B], second PipelinedChannel[B, C]) PipelinedChannel[A, C] {
+	return func(ctx context.Context, inputCh <-chan A) <-chan C {
+		return second(ctx, first(ctx, inputCh))
+	}
+}
+
+func Chain3[A, B, C, D any](s1 PipelinedChannel[A, B], s2 PipelinedChannel[B, C], s3 PipelinedChannel[C, D]) PipelinedChannel[A, D] {
+	return Chain(Chain(s1, s2), s3)
+}
+
+func Chain4[A, B, C, D, E any](s1 PipelinedChannel[A, B], s2 PipelinedChannel[B, C], s3 PipelinedChannel[C, D], s4 PipelinedChannel[D, E]) PipelinedChannel[A, E] {
+	return Chain(Chain3(s1, s2, s3), s4)
+}
+
+// Source превращает slice в канал - удобно как начало pipeline.
+func Source[T any](ctx context.Context, values ...T) <-chan T {
+	outputCh := make(chan T)
+
+	go func() {
+		defer close(outputCh)
+
+		for _, val := range values {
+			if !send(ctx, outputCh, val) {
+				return
+			}
+		}
+	}()
+
+	return outputCh
+}
+
+// send пишет val в ch, false - ctx отменен.
+func send[T any](ctx context.Context, ch chan<- T, val T) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case ch <- val:
+		return true
+	}
+}
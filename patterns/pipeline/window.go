@@ -0,0 +1,297 @@
+package pipeline
+
+import (
+	"cmp"
+	"context"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
+	"slices"
+	"time"
+)
+
+// WindowSpec - вид окна, создается через Tumbling, Sliding или Session.
+type WindowSpec struct {
+	size  time.Duration
+	slide time.Duration
+	gap   time.Duration // > 0 - session
+}
+
+// Tumbling - окна фиксированного размера без пересечений: [00:00, 00:01), [00:01, 00:02)...
+func Tumbling(size time.Duration) WindowSpec {
+	return Sliding(size, size)
+}
+
+// Sliding - окна размера size, начинающиеся каждые slide. Значение попадает в size/slide окон.
+func Sliding(size time.Duration, slide time.Duration) WindowSpec {
+	if size <= 0 || slide <= 0 {
+		panic("size and slide must be greater than 0")
+	}
+	return WindowSpec{size: size, slide: slide}
+}
+
+// Session - окно продолжается, пока значения приходят с паузами меньше gap.
+// Требует Aggregator.Merge: новое значение может соединить две сессии в одну.
+func Session(gap time.Duration) WindowSpec {
+	if gap <= 0 {
+		panic("gap must be greater than 0")
+	}
+	return WindowSpec{gap: gap}
+}
+
+// Aggregator - инкрементальная агрегация окна: Init создает аккумулятор, Add добавляет значение.
+// Merge объединяет два аккумулятора, нужен только для Session.
+type Aggregator[T, R any] struct {
+	Init  func() R
+	Add   func(acc R, val T) R
+	Merge func(a, b R) R
+}
+
+// WindowResult - результат агрегации окна [Start, End).
+type WindowResult[R any] struct {
+	Start time.Time
+	End   time.Time
+	Count int
+	Value R
+}
+
+// WindowConfig - параметры Window.
+//
+// Время:
+// EventTime - время из самого значения (когда событие произошло), nil - processing time (когда значение пришло).
+// По event time результат не зависит от задержек доставки, но значения приходят не по порядку.
+//
+// Watermark - "все события до этого момента уже пришли". Для event time это максимальное увиденное время
+// минус MaxOutOfOrderness, для processing time - просто текущее время.
+// Окно выдается один раз, когда watermark >= End + AllowedLateness. Значение, все окна которого уже выданы,
+// считается опоздавшим и передается в OnLate (может быть nil).
+type WindowConfig[T any] struct {
+	Spec              WindowSpec
+	EventTime         func(T) time.Time
+	MaxOutOfOrderness time.Duration
+	AllowedLateness   time.Duration
+	OnLate            func(T)
+	Clock             clock.Clock   // nil - clock.Real
+	Tick              time.Duration // как часто двигать watermark по processing time без новых значений, 0 - 100ms
+}
+
+const defaultWindowTick = 100 * time.Millisecond
+
+// Window группирует значения по окнам и выдает результат Aggregator для каждого окна.
+// Окна выдаются в порядке End. При закрытии input выдаются все оставшиеся окна.
+func Window[T, R any](config WindowConfig[T], agg Aggregator[T, R], opts ...StageOptionFunc) PipelinedChannel[T, WindowResult[R]] {
+	if agg.Init == nil || agg.Add == nil {
+		panic("aggregator requires Init and Add")
+	}
+	if config.Spec.gap > 0 && agg.Merge == nil {
+		panic("session windows require Aggregator.Merge")
+	}
+	if config.Spec.gap == 0 && config.Spec.size == 0 {
+		panic("window spec is required")
+	}
+
+	return func(ctx context.Context, inputCh <-chan T) <-chan WindowResult[R] {
+		outputCh := make(chan WindowResult[R])
+		p := newProbe(opts, inputCh, (<-chan WindowResult[R])(outputCh))
+
+		go func() {
+			defer close(outputCh)
+
+			w := &windower[T, R]{
+				config: config,
+				agg:    agg,
+				clock:  clock.OrReal(config.Clock),
+				fixed:  make(map[int64]*windowState[R]),
+			}
+
+			// по processing time окна должны закрываться и без новых значений
+			var tickCh <-chan time.Time
+			if config.EventTime == nil {
+				ticker := w.clock.NewTicker(cmp.Or(config.Tick, defaultWindowTick))
+				defer ticker.Stop()
+				tickCh = ticker.C()
+			}
+
+			flush := func(results []WindowResult[R]) bool {
+				for _, res := range results {
+					if !emit(ctx, p, outputCh, res) {
+						return false
+					}
+				}
+				return true
+			}
+
+			// ожидание input считаем вручную: здесь select еще и по ticker
+			for {
+				start := p.now()
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-tickCh:
+					w.watermark = w.clock.Now()
+				case val, ok := <-inputCh:
+					if !ok {
+						flush(w.take(true))
+						return
+					}
+					p.received(start)
+
+					start = p.now()
+					w.add(val)
+					p.processed(start)
+				}
+
+				if !flush(w.take(false)) {
+					return
+				}
+			}
+		}()
+
+		return outputCh
+	}
+}
+
+type windowState[R any] struct {
+	start time.Time
+	end   time.Time
+	count int
+	acc   R
+}
+
+// windower - состояние Window, используется только из одной goroutine.
+type windower[T, R any] struct {
+	config    WindowConfig[T]
+	agg       Aggregator[T, R]
+	clock     clock.Clock
+	watermark time.Time
+	maxEvent  time.Time
+
+	fixed    map[int64]*windowState[R] // tumbling и sliding по началу окна
+	sessions []*windowState[R]
+}
+
+func (w *windower[T, R]) add(val T) {
+	var t time.Time
+	if w.config.EventTime != nil {
+		t = w.config.EventTime(val)
+		if t.After(w.maxEvent) {
+			w.maxEvent = t
+			w.watermark = maxTime(w.watermark, t.Add(-w.config.MaxOutOfOrderness))
+		}
+	} else {
+		t = w.clock.Now()
+		w.watermark = t
+	}
+
+	var added bool
+	if w.config.Spec.gap > 0 {
+		added = w.addSession(t, val)
+	} else {
+		added = w.addFixed(t, val)
+	}
+
+	if !added && w.config.OnLate != nil {
+		w.config.OnLate(val)
+	}
+}
+
+func (w *windower[T, R]) addFixed(t time.Time, val T) bool {
+	spec := w.config.Spec
+	var added bool
+
+	// все окна [start, start+size), содержащие t: начала кратны slide
+	for start := t.Truncate(spec.slide); start.Add(spec.size).After(t); start = start.Add(-spec.slide) {
+		end := start.Add(spec.size)
+		if w.closed(end) {
+			continue
+		}
+
+		state, ok := w.fixed[start.UnixNano()]
+		if !ok {
+			state = &windowState[R]{start: start, end: end, acc: w.agg.Init()}
+			w.fixed[start.UnixNano()] = state
+		}
+
+		state.acc = w.agg.Add(state.acc, val)
+		state.count++
+		added = true
+	}
+
+	return added
+}
+
+func (w *windower[T, R]) addSession(t time.Time, val T) bool {
+	gap := w.config.Spec.gap
+
+	merged := &windowState[R]{start: t, end: t.Add(gap), count: 1, acc: w.agg.Add(w.agg.Init(), val)}
+	if w.closed(merged.end) {
+		return false
+	}
+
+	// новое значение может пересечься с несколькими сессиями и склеить их
+	rest := w.sessions[:0]
+	for _, s := range w.sessions {
+		if s.start.Before(merged.end) && merged.start.Before(s.end) {
+			merged.start = minTime(merged.start, s.start)
+			merged.end = maxTime(merged.end, s.end)
+			merged.count += s.count
+			merged.acc = w.agg.Merge(s.acc, merged.acc)
+			continue
+		}
+		rest = append(rest, s)
+	}
+	w.sessions = append(rest, merged)
+
+	return true
+}
+
+// closed - окно с таким концом уже выдано (или будет выдано сейчас).
+func (w *windower[T, R]) closed(end time.Time) bool {
+	return !end.Add(w.config.AllowedLateness).After(w.watermark)
+}
+
+// take забирает готовые окна (все, если all) в порядке End.
+func (w *windower[T, R]) take(all bool) []WindowResult[R] {
+	var ready []*windowState[R]
+
+	for key, s := range w.fixed {
+		if all || w.closed(s.end) {
+			ready = append(ready, s)
+			delete(w.fixed, key)
+		}
+	}
+
+	rest := w.sessions[:0]
+	for _, s := range w.sessions {
+		if all || w.closed(s.end) {
+			ready = append(ready, s)
+			continue
+		}
+		rest = append(rest, s)
+	}
+	w.sessions = rest
+
+	slices.SortFunc(ready, func(a, b *windowState[R]) int {
+		return cmp.Or(a.end.Compare(b.end), a.start.Compare(b.start))
+	})
+
+	results := make([]WindowResult[R], 0, len(ready))
+	for _, s := range ready {
+		results = append(results, WindowResult[R]{Start: s.start, End: s.end, Count: s.count, Value: s.acc})
+	}
+
+	return results
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
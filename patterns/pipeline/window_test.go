@@ -0,0 +1,128 @@
+package pipeline
+
+import (
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
+	"slices"
+	"testing"
+	"time"
+)
+
+type event struct {
+	at  time.Duration // от base
+	val int
+}
+
+var base = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var sumAgg = Aggregator[event, int]{
+	Init:  func() int { return 0 },
+	Add:   func(acc int, e event) int { return acc + e.val },
+	Merge: func(a, b int) int { return a + b },
+}
+
+func eventTime(e event) time.Time {
+	return base.Add(e.at)
+}
+
+// summary - "start-end:sum" в секундах от base, чтобы сравнивать результаты одной строкой.
+func summary(results []WindowResult[int]) []string {
+	var res []string
+	for _, r := range results {
+		res = append(res, r.Start.Sub(base).String()+"-"+r.End.Sub(base).String()+":"+time.Duration(r.Value).String())
+	}
+	return res
+}
+
+func TestWindow(t *testing.T) {
+	s := time.Second
+
+	cases := []struct {
+		name   string
+		config WindowConfig[event]
+		events []event
+		want   []string
+	}{
+		{
+			name:   "tumbling",
+			config: WindowConfig[event]{Spec: Tumbling(time.Minute)},
+			events: []event{{0, 1}, {10 * s, 2}, {59 * s, 3}, {60 * s, 4}, {130 * s, 5}},
+			want:   []string{"0s-1m0s:6ns", "1m0s-2m0s:4ns", "2m0s-3m0s:5ns"},
+		},
+		{
+			name:   "sliding",
+			config: WindowConfig[event]{Spec: Sliding(time.Minute, 30*s)},
+			events: []event{{10 * s, 1}, {40 * s, 2}, {70 * s, 4}},
+			want:   []string{"-30s-30s:1ns", "0s-1m0s:3ns", "30s-1m30s:6ns", "1m0s-2m0s:4ns"},
+		},
+		{
+			name:   "session",
+			config: WindowConfig[event]{Spec: Session(10 * s), MaxOutOfOrderness: 10 * s},
+			// 0 и 5 - одна сессия, 30 и 45 - две разные, 38 склеивает их
+			events: []event{{0, 1}, {5 * s, 2}, {30 * s, 4}, {45 * s, 8}, {38 * s, 16}},
+			want:   []string{"0s-15s:3ns", "30s-55s:28ns"},
+		},
+		{
+			name:   "late_dropped",
+			config: WindowConfig[event]{Spec: Tumbling(time.Minute)},
+			events: []event{{10 * s, 1}, {70 * s, 2}, {20 * s, 4}},
+			want:   []string{"0s-1m0s:1ns", "1m0s-2m0s:2ns"},
+		},
+		{
+			name:   "allowed_lateness",
+			config: WindowConfig[event]{Spec: Tumbling(time.Minute), AllowedLateness: 30 * s},
+			events: []event{{10 * s, 1}, {70 * s, 2}, {20 * s, 4}, {95 * s, 8}, {30 * s, 16}},
+			want:   []string{"0s-1m0s:5ns", "1m0s-2m0s:10ns"},
+		},
+		{
+			name:   "out_of_orderness",
+			config: WindowConfig[event]{Spec: Tumbling(time.Minute), MaxOutOfOrderness: 15 * s},
+			events: []event{{10 * s, 1}, {70 * s, 2}, {50 * s, 4}},
+			want:   []string{"0s-1m0s:5ns", "1m0s-2m0s:2ns"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := t.Context()
+
+			var late []int
+			tc.config.EventTime = eventTime
+			tc.config.OnLate = func(e event) { late = append(late, e.val) }
+
+			got := summary(collect(Window(tc.config, sumAgg)(ctx, Source(ctx, tc.events...))))
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v (late %v)", got, tc.want, late)
+			}
+		})
+	}
+}
+
+func TestWindowProcessingTime(t *testing.T) {
+	c := clock.NewFakeAt(base)
+
+	inputCh := make(chan event)
+	defer close(inputCh)
+
+	outputCh := Window(WindowConfig[event]{
+		Spec:  Tumbling(time.Minute),
+		Clock: c,
+		Tick:  time.Second,
+	}, sumAgg)(t.Context(), inputCh)
+
+	c.BlockUntil(1)
+	inputCh <- event{val: 1}
+	c.Advance(30 * time.Second)
+	inputCh <- event{val: 2}
+
+	// окно закрывается по тику, новых значений и закрытия input не нужно
+	c.Advance(30 * time.Second)
+
+	select {
+	case res := <-outputCh:
+		if res.Value != 3 || !res.Start.Equal(base) {
+			t.Errorf("got %+v, want sum 3 from %v", res, base)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("window was not emitted")
+	}
+}
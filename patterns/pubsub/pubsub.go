@@ -0,0 +1,195 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
+	"github.com/gallyamow/golang-just-for-fun/patterns/fanout"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrBrokerClosed   = errors.New("broker is closed")
+	ErrSlowSubscriber = fanout.ErrSlowSubscriber
+	ErrUnsubscribed   = fanout.ErrUnsubscribed
+)
+
+// Broker - in-process pub/sub: компоненты общаются через топики, не зная друг о друге.
+//
+// Требования:
+//   - Publish отправляет сообщение всем подписчикам, шаблон которых подходит под топик (см. topic.go)
+//   - у каждого подписчика своя ограниченная очередь и своя fanout.OverflowPolicy,
+//     поэтому медленный подписчик тормозит Publish, только если сам выбрал Block
+//   - доставка at-most-once (по умолчанию) или at-least-once (WithAtLeastOnce): сообщение нужно подтвердить Ack,
+//     иначе после Nack или ack timeout оно будет доставлено повторно
+//   - Close перестает принимать новые сообщения и ждет, пока подписчики получат и подтвердят уже принятые
+//
+// Как и в fanout.Broadcaster, значения раздаются каждому подписчику, но в отличие от него
+// подписчик выбирает топики, а каждое сообщение учитывается отдельно (Ack/Nack).
+//
+// Почему очередь подписчика своя, а не fanout.Broadcaster:
+// Из fanout берутся OverflowPolicy (с той же семантикой) и ошибки отключения. Сама доставка на каналах
+// из fanout не выражается:
+//   - очередь Broadcaster - буферизированный канал, а повторная доставка возвращает сообщение в начало очереди
+//   - лимит очереди учитывает неподтвержденные сообщения, которых в канале уже нет
+//   - ack timeout отсчитывается с момента, когда подписчик забрал сообщение, а из буфера канала этот момент не узнать
+//   - один Broadcaster на все топики не подходит: чужие сообщения занимали бы очередь, а Block одного
+//     подписчика тормозил бы Publish в топики, на которые он не подписан
+//
+// Поэтому очередь - срез под mutex, а у каждой Subscription своя goroutine доставки: она берет сообщение
+// из начала очереди и пишет его в небуферизированный C в select с остановкой подписки.
+type Broker[T any] struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription[T]]struct{}
+	closed bool
+
+	clock  clock.Clock
+	nextID atomic.Uint64
+}
+
+func NewBroker[T any](opts ...BrokerOptionFunc) *Broker[T] {
+	var o brokerOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Broker[T]{
+		subs:  make(map[*Subscription[T]]struct{}),
+		clock: clock.OrReal(o.clock),
+	}
+}
+
+// Publish отправляет payload подписчикам топика. Топик не может содержать wildcards.
+// Блокируется только на подписчиках с политикой Block, отмена ctx прерывает ожидание
+// (подписчики, до которых дошла очередь раньше, сообщение уже получили).
+func (b *Broker[T]) Publish(ctx context.Context, topic string, payload T) error {
+	parts, err := validateTopic(topic)
+	if err != nil {
+		return err
+	}
+
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return ErrBrokerClosed
+	}
+	var subs []*Subscription[T]
+	for s := range b.subs {
+		if match(s.pattern, parts) {
+			subs = append(subs, s)
+		}
+	}
+	b.mu.RUnlock()
+
+	id := b.nextID.Add(1)
+	for _, s := range subs {
+		msg := &Message[T]{ID: id, Topic: topic, Payload: payload, sub: s}
+		if err := s.enqueue(ctx, msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Subscribe подписывает на топики по шаблону, сообщения читаются из Subscription.C.
+func (b *Broker[T]) Subscribe(pattern string, opts ...SubscribeOptionFunc) (*Subscription[T], error) {
+	parts, err := splitTopic(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	o := subscribeOptions{queueSize: defaultQueueSize, overflow: fanout.Block}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.queueSize <= 0 {
+		panic("queue size must be greater than 0")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, ErrBrokerClosed
+	}
+
+	s := newSubscription[T](parts, o, b.clock)
+	b.subs[s] = struct{}{}
+
+	return s, nil
+}
+
+// Unsubscribe сразу отписывает: сообщения в очереди подписчика и неподтвержденные теряются, C закрывается.
+func (b *Broker[T]) Unsubscribe(s *Subscription[T]) {
+	b.mu.Lock()
+	delete(b.subs, s)
+	b.mu.Unlock()
+
+	s.stop(ErrUnsubscribed)
+}
+
+// Close перестает принимать сообщения и ждет, пока все подписчики получат и подтвердят (для at-least-once)
+// уже принятые сообщения, после чего их каналы закрываются. Подписчики должны продолжать читать C.
+// Если ctx отменен раньше, то подписчики останавливаются сразу и возвращается ctx.Err().
+func (b *Broker[T]) Close(ctx context.Context) error {
+	b.mu.Lock()
+	b.closed = true
+	subs := make([]*Subscription[T], 0, len(b.subs))
+	for s := range b.subs {
+		subs = append(subs, s)
+	}
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		s.drain()
+	}
+
+	for _, s := range subs {
+		select {
+		case <-s.finished:
+		case <-ctx.Done():
+			for _, s := range subs {
+				s.stop(ErrBrokerClosed)
+			}
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// Message - сообщение, доставленное подписчику. Attempt - номер доставки (с 1), > 1 - повторная доставка.
+// ID одинаковый у всех подписчиков и всех повторных доставок одного сообщения (для дедупликации).
+type Message[T any] struct {
+	ID      uint64
+	Topic   string
+	Payload T
+	Attempt int
+
+	sub *Subscription[T]
+}
+
+// Ack подтверждает обработку. Для at-most-once ничего не делает.
+// Подтверждение устаревшей доставки (после которой уже была повторная) игнорируется.
+func (m *Message[T]) Ack() {
+	m.sub.settle(m, false)
+}
+
+// Nack возвращает сообщение в начало очереди для повторной доставки. Для at-most-once ничего не делает.
+func (m *Message[T]) Nack() {
+	m.sub.settle(m, true)
+}
+
+type brokerOptions struct {
+	clock clock.Clock
+}
+
+type BrokerOptionFunc func(*brokerOptions)
+
+func WithClock(val clock.Clock) BrokerOptionFunc {
+	return func(o *brokerOptions) {
+		o.clock = val
+	}
+}
@@ -0,0 +1,273 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
+	"github.com/gallyamow/golang-just-for-fun/patterns/fanout"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		topic   string
+		want    bool
+	}{
+		{"orders", "orders", true},
+		{"orders", "orders.created", false},
+		{"orders.*", "orders.created", true},
+		{"orders.*", "orders", false},
+		{"orders.*", "orders.created.eu", false},
+		{"orders.#", "orders", true},
+		{"orders.#", "orders.created.eu", true},
+		{"*.created", "orders.created", true},
+		{"#.eu", "orders.created.eu", true},
+		{"orders.#.eu", "orders.eu", true},
+		{"orders.#.eu", "orders.created.us", false},
+		{"#", "anything.at.all", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"_"+tt.topic, func(t *testing.T) {
+			pattern, _ := splitTopic(tt.pattern)
+			topic, _ := validateTopic(tt.topic)
+
+			if got := match(pattern, topic); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBroker(t *testing.T) {
+	t.Run("routes_by_pattern", func(t *testing.T) {
+		ctx := t.Context()
+		b := NewBroker[string]()
+
+		all, _ := b.Subscribe("orders.#")
+		created, _ := b.Subscribe("orders.created")
+		payments, _ := b.Subscribe("payments.*")
+
+		_ = b.Publish(ctx, "orders.created", "o1")
+		_ = b.Publish(ctx, "orders.paid", "o2")
+		_ = b.Publish(ctx, "payments.failed", "p1")
+
+		// Close ждет, пока подписчики дочитают, поэтому читаем параллельно
+		go func() {
+			_ = b.Close(ctx)
+		}()
+
+		if got := payloads(all); !slices.Equal(got, []string{"o1", "o2"}) {
+			t.Errorf("got %v, want [o1 o2]", got)
+		}
+		if got := payloads(created); !slices.Equal(got, []string{"o1"}) {
+			t.Errorf("got %v, want [o1]", got)
+		}
+		if got := payloads(payments); !slices.Equal(got, []string{"p1"}) {
+			t.Errorf("got %v, want [p1]", got)
+		}
+	})
+
+	t.Run("invalid_topic", func(t *testing.T) {
+		b := NewBroker[string]()
+
+		if err := b.Publish(t.Context(), "orders.*", "x"); !errors.Is(err, ErrInvalidTopic) {
+			t.Errorf("got %v, want ErrInvalidTopic", err)
+		}
+		if _, err := b.Subscribe("orders..created"); !errors.Is(err, ErrInvalidTopic) {
+			t.Errorf("got %v, want ErrInvalidTopic", err)
+		}
+	})
+
+	t.Run("nack_redelivers", func(t *testing.T) {
+		ctx := t.Context()
+		b := NewBroker[string]()
+		s, _ := b.Subscribe("jobs", WithAtLeastOnce(time.Hour, 0))
+
+		_ = b.Publish(ctx, "jobs", "j1")
+
+		first := <-s.C
+		first.Nack()
+
+		second := <-s.C
+		if second.ID != first.ID || second.Attempt != 2 {
+			t.Errorf("got id %v attempt %v, want id %v attempt 2", second.ID, second.Attempt, first.ID)
+		}
+
+		// подтверждение старой доставки не подтверждает новую
+		first.Ack()
+		if s.Lag() != 1 {
+			t.Errorf("got lag %v, want 1", s.Lag())
+		}
+
+		second.Ack()
+		if s.Lag() != 0 {
+			t.Errorf("got lag %v, want 0", s.Lag())
+		}
+	})
+
+	t.Run("ack_timeout_redelivers", func(t *testing.T) {
+		ctx := t.Context()
+		clk := clock.NewFake()
+		b := NewBroker[string](WithClock(clk))
+		s, _ := b.Subscribe("jobs", WithAtLeastOnce(time.Second, 2))
+
+		_ = b.Publish(ctx, "jobs", "j1")
+
+		<-s.C
+		clk.BlockUntil(1)
+		clk.Advance(time.Second)
+
+		second := <-s.C
+		if second.Attempt != 2 {
+			t.Errorf("got attempt %v, want 2", second.Attempt)
+		}
+
+		// вторая доставка последняя
+		clk.BlockUntil(1)
+		clk.Advance(time.Second)
+
+		if s.Dropped() != 1 || s.Lag() != 0 {
+			t.Errorf("got dropped %v lag %v, want 1 and 0", s.Dropped(), s.Lag())
+		}
+	})
+
+	t.Run("unacked_messages_fill_queue", func(t *testing.T) {
+		ctx := t.Context()
+		b := NewBroker[int]()
+		s, _ := b.Subscribe("n", WithQueueSize(2), WithOverflow(fanout.DropNewest), WithAtLeastOnce(time.Hour, 0))
+
+		for i := range 5 {
+			_ = b.Publish(ctx, "n", i)
+		}
+
+		// 2 места, из них одно занимает доставленное, но неподтвержденное
+		msg := <-s.C
+		<-s.C
+		if s.Dropped() != 3 {
+			t.Errorf("got dropped %v, want 3", s.Dropped())
+		}
+
+		msg.Ack()
+		_ = b.Publish(ctx, "n", 5)
+		if got := <-s.C; got.Payload != 5 {
+			t.Errorf("got %v, want 5", got.Payload)
+		}
+	})
+
+	t.Run("slow_subscriber_disconnected", func(t *testing.T) {
+		ctx := t.Context()
+		b := NewBroker[int]()
+		slow, _ := b.Subscribe("n", WithQueueSize(1), WithOverflow(fanout.Disconnect))
+		fast, _ := b.Subscribe("n", WithQueueSize(10))
+
+		for i := range 3 {
+			if err := b.Publish(ctx, "n", i); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for range slow.C {
+		}
+		if !errors.Is(slow.Err(), ErrSlowSubscriber) {
+			t.Errorf("got %v, want ErrSlowSubscriber", slow.Err())
+		}
+
+		go func() {
+			_ = b.Close(ctx)
+		}()
+		if got := payloads(fast); !slices.Equal(got, []int{0, 1, 2}) {
+			t.Errorf("got %v, want [0 1 2]", got)
+		}
+	})
+
+	t.Run("close_waits_for_ack", func(t *testing.T) {
+		ctx := t.Context()
+		b := NewBroker[string]()
+		s, _ := b.Subscribe("jobs", WithAtLeastOnce(time.Hour, 0))
+
+		_ = b.Publish(ctx, "jobs", "j1")
+		msg := <-s.C
+
+		closed := make(chan error)
+		go func() {
+			closed <- b.Close(ctx)
+		}()
+
+		select {
+		case <-closed:
+			t.Fatal("closed before ack")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		if err := b.Publish(ctx, "jobs", "j2"); !errors.Is(err, ErrBrokerClosed) {
+			t.Errorf("got %v, want ErrBrokerClosed", err)
+		}
+
+		msg.Ack()
+		if err := <-closed; err != nil {
+			t.Errorf("got %v, want nil", err)
+		}
+		if _, ok := <-s.C; ok {
+			t.Error("channel is not closed")
+		}
+	})
+
+	t.Run("close_context_timeout", func(t *testing.T) {
+		b := NewBroker[string]()
+		s, _ := b.Subscribe("jobs")
+		_ = b.Publish(t.Context(), "jobs", "never read")
+
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+		defer cancel()
+
+		if err := b.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got %v, want context.DeadlineExceeded", err)
+		}
+		if _, ok := <-s.C; ok {
+			t.Error("channel is not closed")
+		}
+	})
+
+	t.Run("unsubscribe_unblocks_publish", func(t *testing.T) {
+		ctx := t.Context()
+		b := NewBroker[int]()
+		s, _ := b.Subscribe("n", WithQueueSize(1))
+
+		_ = b.Publish(ctx, "n", 1)
+		_ = b.Publish(ctx, "n", 2) // первое уже ждет в C, второе в очереди
+
+		published := make(chan error)
+		go func() {
+			published <- b.Publish(ctx, "n", 3)
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		b.Unsubscribe(s)
+
+		select {
+		case err := <-published:
+			if err != nil {
+				t.Errorf("got %v, want nil", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("publish is still blocked")
+		}
+
+		if !errors.Is(s.Err(), ErrUnsubscribed) {
+			t.Errorf("got %v, want ErrUnsubscribed", s.Err())
+		}
+	})
+}
+
+func payloads[T any](s *Subscription[T]) []T {
+	var res []T
+	for msg := range s.C {
+		msg.Ack()
+		res = append(res, msg.Payload)
+	}
+	return res
+}
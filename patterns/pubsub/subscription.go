@@ -0,0 +1,296 @@
+package pubsub
+
+import (
+	"context"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
+	"github.com/gallyamow/golang-just-for-fun/patterns/fanout"
+	"slices"
+	"sync"
+	"time"
+)
+
+const defaultQueueSize = 64
+
+// Subscription - подписка на Broker. Сообщения читаются из C, канал закрывается после отписки или Close.
+//
+// Очередь:
+// Ограничение queueSize действует на сообщения в очереди вместе с неподтвержденными (at-least-once),
+// поэтому подписчик, который не подтверждает сообщения, тоже создает backpressure.
+// Повторная доставка возвращает сообщение в начало очереди, при этом место не занимает (оно уже учтено).
+type Subscription[T any] struct {
+	C <-chan *Message[T]
+
+	pattern []string
+	opts    subscribeOptions
+	clock   clock.Clock
+	out     chan *Message[T]
+
+	mu       sync.Mutex
+	queue    []*Message[T]
+	inflight map[*Message[T]]clock.Timer // доставленные и неподтвержденные, timer - ack timeout
+	changed  chan struct{}               // закрывается и пересоздается при любом изменении (broadcast)
+	draining bool
+	dropped  uint64
+	err      error
+
+	done     chan struct{} // закрывается при stop
+	finished chan struct{} // закрывается, когда goroutine доставки завершилась
+}
+
+func newSubscription[T any](pattern []string, opts subscribeOptions, clk clock.Clock) *Subscription[T] {
+	out := make(chan *Message[T])
+
+	s := &Subscription[T]{
+		C:        out,
+		pattern:  pattern,
+		opts:     opts,
+		clock:    clk,
+		out:      out,
+		inflight: make(map[*Message[T]]clock.Timer),
+		changed:  make(chan struct{}),
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+// Lag - сколько сообщений ждут доставки или подтверждения.
+func (s *Subscription[T]) Lag() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.queue) + len(s.inflight)
+}
+
+// Dropped - сколько сообщений потеряно из-за переполнения очереди или превышения количества доставок.
+func (s *Subscription[T]) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.dropped
+}
+
+// Err - причина закрытия C: ErrUnsubscribed, ErrSlowSubscriber или ErrBrokerClosed. nil - подписка активна.
+func (s *Subscription[T]) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// run доставляет сообщения из очереди в C по одному.
+func (s *Subscription[T]) run() {
+	defer close(s.finished)
+	defer close(s.out)
+
+	for {
+		s.mu.Lock()
+		for len(s.queue) == 0 {
+			if s.err != nil || (s.draining && len(s.inflight) == 0) {
+				if s.err == nil {
+					s.err = ErrBrokerClosed
+				}
+				s.mu.Unlock()
+				return
+			}
+
+			changed := s.changed
+			s.mu.Unlock()
+
+			select {
+			case <-changed:
+			case <-s.done:
+			}
+			s.mu.Lock()
+		}
+
+		msg := s.queue[0]
+		s.queue = s.queue[1:]
+		if s.opts.atLeastOnce {
+			// timer ставится после передачи подписчику: время ожидания в C не считается
+			s.inflight[msg] = nil
+		} else {
+			s.notify()
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-s.done:
+			return
+		case s.out <- msg:
+		}
+
+		if s.opts.atLeastOnce {
+			s.mu.Lock()
+			if _, ok := s.inflight[msg]; ok {
+				s.inflight[msg] = s.clock.AfterFunc(s.opts.ackTimeout, func() {
+					s.settle(msg, true)
+				})
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// enqueue добавляет сообщение в очередь с учетом OverflowPolicy.
+func (s *Subscription[T]) enqueue(ctx context.Context, msg *Message[T]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.queue)+len(s.inflight) >= s.opts.queueSize {
+		if s.err != nil {
+			return nil
+		}
+
+		switch s.opts.overflow {
+		case fanout.DropNewest:
+			s.dropped++
+			return nil
+		case fanout.DropOldest:
+			if len(s.queue) == 0 {
+				// место занято неподтвержденными, их выбросить нельзя
+				s.dropped++
+				return nil
+			}
+			s.queue = s.queue[1:]
+			s.dropped++
+		case fanout.Disconnect:
+			s.dropped++
+			s.stopLocked(ErrSlowSubscriber)
+			return nil
+		default:
+			changed := s.changed
+			s.mu.Unlock()
+
+			select {
+			case <-ctx.Done():
+				s.mu.Lock()
+				return ctx.Err()
+			case <-s.done:
+			case <-changed:
+			}
+			s.mu.Lock()
+		}
+	}
+
+	if s.err != nil {
+		return nil
+	}
+
+	msg.Attempt = 1
+	s.queue = append(s.queue, msg)
+	s.notify()
+
+	return nil
+}
+
+// settle - Ack (redeliver = false) или Nack/ack timeout (redeliver = true).
+func (s *Subscription[T]) settle(msg *Message[T], redeliver bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	timer, ok := s.inflight[msg]
+	if !ok {
+		return
+	}
+	if timer != nil {
+		timer.Stop()
+	}
+	delete(s.inflight, msg)
+	s.notify()
+
+	if !redeliver || s.err != nil {
+		return
+	}
+
+	if s.opts.maxDeliveries > 0 && msg.Attempt >= s.opts.maxDeliveries {
+		s.dropped++
+		return
+	}
+
+	// новая доставка - новый *Message: Ack старой доставки не должен подтвердить новую
+	next := *msg
+	next.Attempt++
+	s.queue = slices.Insert(s.queue, 0, &next)
+}
+
+// drain - перестать ждать новых сообщений, завершиться после доставки и подтверждения текущих.
+func (s *Subscription[T]) drain() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.draining = true
+	s.notify()
+}
+
+func (s *Subscription[T]) stop(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopLocked(err)
+}
+
+func (s *Subscription[T]) stopLocked(err error) {
+	if s.err != nil {
+		return
+	}
+
+	s.err = err
+	for _, timer := range s.inflight {
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+	s.inflight = make(map[*Message[T]]clock.Timer)
+	s.queue = nil
+	close(s.done)
+	s.notify()
+}
+
+// notify будит всех, кто ждет изменения очереди. Вызывается под mu.
+func (s *Subscription[T]) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+type subscribeOptions struct {
+	queueSize     int
+	overflow      fanout.OverflowPolicy
+	atLeastOnce   bool
+	ackTimeout    time.Duration
+	maxDeliveries int
+}
+
+type SubscribeOptionFunc func(*subscribeOptions)
+
+// WithQueueSize - размер очереди подписчика, по умолчанию 64.
+func WithQueueSize(val int) SubscribeOptionFunc {
+	return func(o *subscribeOptions) {
+		o.queueSize = val
+	}
+}
+
+// WithOverflow - что делать при заполненной очереди, по умолчанию fanout.Block.
+func WithOverflow(val fanout.OverflowPolicy) SubscribeOptionFunc {
+	return func(o *subscribeOptions) {
+		o.overflow = val
+	}
+}
+
+// WithAtLeastOnce включает подтверждения: сообщение без Ack за ackTimeout доставляется повторно.
+// maxDeliveries ограничивает количество доставок одного сообщения (0 - без ограничения), после чего оно теряется.
+// Обработчик должен быть идемпотентным: сообщение может прийти несколько раз (см. Message.ID).
+func WithAtLeastOnce(ackTimeout time.Duration, maxDeliveries int) SubscribeOptionFunc {
+	if ackTimeout <= 0 {
+		panic("ack timeout must be greater than 0")
+	}
+
+	return func(o *subscribeOptions) {
+		o.atLeastOnce = true
+		o.ackTimeout = ackTimeout
+		o.maxDeliveries = maxDeliveries
+	}
+}
@@ -0,0 +1,64 @@
+package pubsub
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidTopic = errors.New("invalid topic")
+
+// Топики иерархические, уровни разделяются точкой: "orders.created.eu".
+// В шаблоне подписки (как в AMQP topic exchange):
+//   - "*" - ровно один уровень: "orders.*" подходит для "orders.created", но не для "orders" и "orders.created.eu"
+//   - "#" - ноль или больше уровней: "orders.#" подходит для "orders", "orders.created" и "orders.created.eu"
+
+func splitTopic(topic string) ([]string, error) {
+	if topic == "" {
+		return nil, ErrInvalidTopic
+	}
+
+	parts := strings.Split(topic, ".")
+	for _, p := range parts {
+		if p == "" {
+			return nil, ErrInvalidTopic
+		}
+	}
+	return parts, nil
+}
+
+// validateTopic - в топике публикации wildcards запрещены.
+func validateTopic(topic string) ([]string, error) {
+	parts, err := splitTopic(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range parts {
+		if p == "*" || p == "#" {
+			return nil, ErrInvalidTopic
+		}
+	}
+	return parts, nil
+}
+
+// match проверяет, подходит ли топик под шаблон.
+func match(pattern []string, topic []string) bool {
+	if len(pattern) == 0 {
+		return len(topic) == 0
+	}
+
+	switch pattern[0] {
+	case "#":
+		// "#" съедает 0..len(topic) уровней
+		for i := 0; i <= len(topic); i++ {
+			if match(pattern[1:], topic[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(topic) > 0 && match(pattern[1:], topic[1:])
+	default:
+		return len(topic) > 0 && pattern[0] == topic[0] && match(pattern[1:], topic[1:])
+	}
+}
@@ -0,0 +1,291 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Распределенные лимитеры - одна квота на несколько реплик сервиса, состояние лежит в общем Store.
+//
+// Проблемы и их решения:
+//   - Каждый запрос = поход в Store. Решение: prefetch - забираем из Store сразу пачку (batch) токенов и
+//     расходуем их локально. Цена: реплика может "придержать" токены, которые другим бы пригодились.
+//   - Store может быть недоступен. Решение: выбор между fail-open (пропускаем всех, защищаем доступность)
+//     и fail-closed (никого не пропускаем, защищаем upstream).
+
+// DistributedWindow - fixed window counter поверх Store.Incr.
+//
+// Идея:
+// Время делится на окна длиной window, для каждого окна в Store свой ключ-счетчик с ttl.
+// Запрос разрешен, если после инкремента счетчик не превысил limit.
+//
+// Минусы:
+// На стыке окон можно пропустить до 2*limit запросов.
+type DistributedWindow struct {
+	store  Store
+	key    string
+	limit  int64
+	window time.Duration
+	config *DistributedConfig
+
+	mu          sync.Mutex
+	local       int64 // токены, забранные из Store заранее
+	localWindow int64 // окно, к которому они относятся
+}
+
+// NewDistributedWindow создает новую структуру.
+func NewDistributedWindow(store Store, key string, limit int, window time.Duration, opts ...DistributedOptionFunc) *DistributedWindow {
+	return &DistributedWindow{
+		store:  store,
+		key:    key,
+		limit:  int64(limit),
+		window: window,
+		config: NewDistributedConfig(opts...),
+	}
+}
+
+// Allow позволяет проверить возможность без блокировки.
+// Ошибка Store обрабатывается согласно FailOpen.
+func (l *DistributedWindow) Allow() bool {
+	ok, err := l.AllowContext(context.Background())
+	if err != nil {
+		return l.config.fail(err)
+	}
+	return ok
+}
+
+// AllowContext то же, что Allow, но с контекстом для Store и с ошибкой как есть.
+func (l *DistributedWindow) AllowContext(ctx context.Context) (bool, error) {
+	// Mutex держим и на время похода в Store: пусть за пачкой сходит одна goroutine, а не все сразу.
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	win := time.Now().UnixNano() / int64(l.window)
+
+	// Токены прошлого окна сгорают
+	if l.localWindow != win {
+		l.local = 0
+		l.localWindow = win
+	}
+
+	if l.local > 0 {
+		l.local--
+		return true, nil
+	}
+
+	batch := l.config.Batch
+	n, err := l.store.Incr(ctx, fmt.Sprintf("%s:%d", l.key, win), batch, l.window)
+	if err != nil {
+		return false, err
+	}
+
+	// Из нашей пачки в лимит поместилось только то, что было до нас свободно.
+	granted := min(l.limit-(n-batch), batch)
+	if granted <= 0 {
+		return false, nil
+	}
+
+	l.local = granted - 1
+
+	return true, nil
+}
+
+// Wait блокирует выполнение до тех пор, пока не будет разрешено.
+func (l *DistributedWindow) Wait(ctx context.Context) error {
+	return distributedWait(ctx, l.AllowContext, l.config, func() time.Duration {
+		// до начала следующего окна
+		return l.window - time.Duration(time.Now().UnixNano()%int64(l.window))
+	})
+}
+
+// DistributedBucket - token bucket поверх Store.CompareAndSet (GCRA, generic cell rate algorithm).
+//
+// Идея:
+// Вместо количества токенов храним одно число - TAT (theoretical arrival time), момент, когда ведро станет полным.
+// Каждый токен сдвигает TAT на interval = 1/rate. Запрос разрешен, если TAT не ушел в будущее дальше, чем на burst*interval.
+// Изменение TAT - read + CAS, при конфликте с другой репликой повторяем.
+//
+// Плюсы:
+// Одно число на ключ, нет фонового пополнения, всплески до burst как у TokenBucket.
+type DistributedBucket struct {
+	store    Store
+	key      string
+	burst    int64
+	interval time.Duration
+	config   *DistributedConfig
+
+	mu    sync.Mutex
+	local int64 // токены, забранные из Store заранее
+}
+
+// NewDistributedBucket создает новую структуру.
+func NewDistributedBucket(store Store, key string, burst int, rate float64, opts ...DistributedOptionFunc) *DistributedBucket {
+	return &DistributedBucket{
+		store:    store,
+		key:      key,
+		burst:    int64(burst),
+		interval: time.Duration(float64(time.Second) / rate),
+		config:   NewDistributedConfig(opts...),
+	}
+}
+
+// Allow позволяет проверить возможность без блокировки.
+// Ошибка Store обрабатывается согласно FailOpen.
+func (l *DistributedBucket) Allow() bool {
+	ok, err := l.AllowContext(context.Background())
+	if err != nil {
+		return l.config.fail(err)
+	}
+	return ok
+}
+
+// AllowContext то же, что Allow, но с контекстом для Store и с ошибкой как есть.
+func (l *DistributedBucket) AllowContext(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.local > 0 {
+		l.local--
+		return true, nil
+	}
+
+	granted, err := l.reserve(ctx, l.config.Batch)
+	if err != nil || granted == 0 {
+		return false, err
+	}
+
+	l.local = granted - 1
+
+	return true, nil
+}
+
+// reserve забирает из Store до n токенов и возвращает сколько удалось.
+func (l *DistributedBucket) reserve(ctx context.Context, n int64) (int64, error) {
+	interval := int64(l.interval)
+	tolerance := l.burst * interval
+
+	for range maxCASAttempts {
+		old, err := l.store.Get(ctx, l.key)
+		if err != nil {
+			return 0, err
+		}
+
+		now := time.Now().UnixNano()
+		tat := max(old, now)
+
+		// сколько токенов сейчас в ведре
+		available := (now + tolerance - tat) / interval
+		granted := min(n, available)
+		if granted <= 0 {
+			return 0, nil
+		}
+
+		newTat := tat + granted*interval
+
+		ok, err := l.store.CompareAndSet(ctx, l.key, old, newTat, time.Duration(newTat-now))
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			return granted, nil
+		}
+		// кто-то успел раньше, перечитываем
+	}
+
+	return 0, ErrStoreContention
+}
+
+// Wait блокирует выполнение до тех пор, пока не будет разрешено.
+func (l *DistributedBucket) Wait(ctx context.Context) error {
+	return distributedWait(ctx, l.AllowContext, l.config, func() time.Duration {
+		return l.interval
+	})
+}
+
+// maxCASAttempts - сколько раз повторяем CAS при конфликтах, прежде чем сдаться.
+const maxCASAttempts = 10
+
+// ErrStoreContention - не удалось выполнить CAS из-за постоянных конфликтов с другими репликами.
+var ErrStoreContention = errors.New("ratelimiter: store contention")
+
+func distributedWait(ctx context.Context, allow func(context.Context) (bool, error), config *DistributedConfig, next func() time.Duration) error {
+	for {
+		ok, err := allow(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if config.fail(err) {
+				return nil
+			}
+			return err
+		}
+		if ok {
+			return nil
+		}
+
+		timer := time.NewTimer(next())
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+type DistributedConfig struct {
+	Batch        int64       // Сколько токенов забирать из Store за раз (1 - без prefetch)
+	FailOpen     bool        // При ошибке Store: true - пропускать, false - отклонять
+	OnStoreError func(error) // Вызывается при каждой ошибке Store (логирование, метрики)
+}
+
+func DefaultDistributedConfig() *DistributedConfig {
+	return &DistributedConfig{
+		Batch:        1,
+		FailOpen:     false,
+		OnStoreError: func(error) {},
+	}
+}
+
+func NewDistributedConfig(opts ...DistributedOptionFunc) *DistributedConfig {
+	config := DefaultDistributedConfig()
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
+
+// fail сообщает об ошибке и возвращает решение согласно политике.
+func (c *DistributedConfig) fail(err error) bool {
+	c.OnStoreError(err)
+	return c.FailOpen
+}
+
+type DistributedOptionFunc func(*DistributedConfig)
+
+func WithBatch(val int) DistributedOptionFunc {
+	if val < 1 {
+		panic("batch must be greater than 0, pass 1 if you want to disable prefetch")
+	}
+
+	return func(c *DistributedConfig) {
+		c.Batch = int64(val)
+	}
+}
+
+func WithFailOpen(val bool) DistributedOptionFunc {
+	return func(c *DistributedConfig) {
+		c.FailOpen = val
+	}
+}
+
+func WithStoreErrorHandler(val func(error)) DistributedOptionFunc {
+	return func(c *DistributedConfig) {
+		c.OnStoreError = val
+	}
+}
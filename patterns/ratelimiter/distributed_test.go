@@ -0,0 +1,157 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDistributedWindow(t *testing.T) {
+	t.Run("shared_quota", func(t *testing.T) {
+		store := NewMemoryStore()
+		r1 := NewDistributedWindow(store, "api", 10, time.Hour)
+		r2 := NewDistributedWindow(store, "api", 10, time.Hour)
+
+		if got := allowedCount(20, r1, r2); got != 10 {
+			t.Errorf("got %v allowed, want 10", got)
+		}
+	})
+
+	t.Run("batch_reduces_round_trips", func(t *testing.T) {
+		store := &countingStore{Store: NewMemoryStore()}
+		r := NewDistributedWindow(store, "api", 100, time.Hour, WithBatch(5))
+
+		for i := 0; i < 10; i++ {
+			if !r.Allow() {
+				t.Fatalf("must be allowed")
+			}
+		}
+
+		if calls := store.calls.Load(); calls != 2 {
+			t.Errorf("got %v store calls, want 2", calls)
+		}
+	})
+
+	t.Run("batch_does_not_exceed_limit", func(t *testing.T) {
+		store := NewMemoryStore()
+		r1 := NewDistributedWindow(store, "api", 10, time.Hour, WithBatch(4))
+		r2 := NewDistributedWindow(store, "api", 10, time.Hour, WithBatch(4))
+
+		if got := allowedCount(20, r1, r2); got != 10 {
+			t.Errorf("got %v allowed, want 10", got)
+		}
+	})
+
+	t.Run("fail_policy", func(t *testing.T) {
+		failPolicy(t, func(opts ...DistributedOptionFunc) RateLimiter {
+			return NewDistributedWindow(errStore{}, "api", 10, time.Hour, opts...)
+		})
+	})
+}
+
+func TestDistributedBucket(t *testing.T) {
+	t.Run("shared_quota", func(t *testing.T) {
+		store := NewMemoryStore()
+		r1 := NewDistributedBucket(store, "api", 5, 1)
+		r2 := NewDistributedBucket(store, "api", 5, 1)
+
+		if got := allowedCount(20, r1, r2); got != 5 {
+			t.Errorf("got %v allowed, want 5", got)
+		}
+	})
+
+	t.Run("batch_does_not_exceed_burst", func(t *testing.T) {
+		store := NewMemoryStore()
+		r1 := NewDistributedBucket(store, "api", 5, 1, WithBatch(3))
+		r2 := NewDistributedBucket(store, "api", 5, 1, WithBatch(3))
+
+		if got := allowedCount(20, r1, r2); got != 5 {
+			t.Errorf("got %v allowed, want 5", got)
+		}
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		r := NewDistributedBucket(NewMemoryStore(), "api", 1, 20)
+
+		if err := r.Wait(t.Context()); err != nil {
+			t.Fatalf("got error %v", err)
+		}
+
+		ts := time.Now()
+		if err := r.Wait(t.Context()); err != nil {
+			t.Fatalf("got error %v", err)
+		}
+
+		if elapsed := time.Since(ts); elapsed < 40*time.Millisecond || elapsed > 100*time.Millisecond {
+			t.Errorf("got %v, want about 50ms", elapsed)
+		}
+	})
+
+	t.Run("fail_policy", func(t *testing.T) {
+		failPolicy(t, func(opts ...DistributedOptionFunc) RateLimiter {
+			return NewDistributedBucket(errStore{}, "api", 5, 1, opts...)
+		})
+	})
+}
+
+func failPolicy(t *testing.T, newLimiter func(opts ...DistributedOptionFunc) RateLimiter) {
+	var reported int
+	closed := newLimiter(WithStoreErrorHandler(func(error) { reported++ }))
+
+	if closed.Allow() {
+		t.Errorf("fail-closed must not allow")
+	}
+	if err := closed.Wait(t.Context()); err == nil {
+		t.Errorf("fail-closed must return error")
+	}
+	if reported != 2 {
+		t.Errorf("got %v reported errors, want 2", reported)
+	}
+
+	open := newLimiter(WithFailOpen(true))
+
+	if !open.Allow() {
+		t.Errorf("fail-open must allow")
+	}
+	if err := open.Wait(t.Context()); err != nil {
+		t.Errorf("fail-open must not return error, got %v", err)
+	}
+}
+
+func allowedCount(n int, limiters ...RateLimiter) int {
+	allowed := 0
+	for i := 0; i < n; i++ {
+		if limiters[i%len(limiters)].Allow() {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+type countingStore struct {
+	Store
+	calls atomic.Int64
+}
+
+func (s *countingStore) Incr(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	s.calls.Add(1)
+	return s.Store.Incr(ctx, key, delta, ttl)
+}
+
+type errStore struct{}
+
+var errUnavailable = errors.New("store unavailable")
+
+func (errStore) Get(context.Context, string) (int64, error) {
+	return 0, errUnavailable
+}
+
+func (errStore) Incr(context.Context, string, int64, time.Duration) (int64, error) {
+	return 0, errUnavailable
+}
+
+func (errStore) CompareAndSet(context.Context, string, int64, int64, time.Duration) (bool, error) {
+	return false, errUnavailable
+}
@@ -0,0 +1,97 @@
+package ratelimiter
+
+import (
+	"context"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
+	"sync"
+	"time"
+)
+
+// KeyedLimiter - набор лимитеров, по одному на ключ (IP, пользователь, маршрут и т.д.).
+//
+// Лимитер для ключа создается лениво при первом обращении через фабрику newLimiter,
+// поэтому у каждого ключа своя независимая квота.
+//
+// Минусы:
+// Количество ключей не ограничено, для долгоживущего процесса нужно включать UseJanitor.
+type KeyedLimiter struct {
+	newLimiter func() RateLimiter
+	limiters   map[string]*keyedItem
+	mu         sync.Mutex
+	clock      clock.Clock
+}
+
+type keyedItem struct {
+	limiter  RateLimiter
+	lastSeen time.Time
+}
+
+// NewKeyedLimiter создает новую структуру.
+// clock.With задает часы для учета простоя ключей в UseJanitor, на лимитеры из newLimiter не влияет.
+func NewKeyedLimiter(newLimiter func() RateLimiter, opts ...clock.Option) *KeyedLimiter {
+	clk := clock.FromOptions(opts)
+
+	return &KeyedLimiter{
+		newLimiter: newLimiter,
+		limiters:   make(map[string]*keyedItem),
+		clock:      clk,
+	}
+}
+
+// Get возвращает лимитер для ключа, создавая его при необходимости.
+func (kl *KeyedLimiter) Get(key string) RateLimiter {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+
+	item, ok := kl.limiters[key]
+	if !ok {
+		item = &keyedItem{limiter: kl.newLimiter()}
+		kl.limiters[key] = item
+	}
+	item.lastSeen = kl.clock.Now()
+
+	return item.limiter
+}
+
+// Allow позволяет проверить возможность без блокировки для указанного ключа.
+func (kl *KeyedLimiter) Allow(key string) bool {
+	return kl.Get(key).Allow()
+}
+
+// Wait блокирует выполнение до тех пор, пока не будет разрешено для указанного ключа.
+func (kl *KeyedLimiter) Wait(ctx context.Context, key string) error {
+	return kl.Get(key).Wait(ctx)
+}
+
+// Len возвращает количество отслеживаемых ключей.
+func (kl *KeyedLimiter) Len() int {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+
+	return len(kl.limiters)
+}
+
+// UseJanitor раз в tick удаляет лимитеры, к которым не обращались дольше idle.
+// Удаленный ключ при следующем обращении получит новый (полный) лимитер, поэтому idle стоит брать
+// не меньше времени полного восстановления квоты.
+func (kl *KeyedLimiter) UseJanitor(ctx context.Context, tick time.Duration, idle time.Duration) {
+	go func() {
+		ticker := kl.clock.NewTicker(tick)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				kl.mu.Lock()
+				for key, item := range kl.limiters {
+					if kl.clock.Since(item.lastSeen) > idle {
+						delete(kl.limiters, key)
+					}
+				}
+				kl.mu.Unlock()
+			}
+		}
+	}()
+}
@@ -2,6 +2,7 @@ package ratelimiter
 
 import (
 	"context"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
 	"sync"
 	"time"
 )
@@ -34,13 +35,19 @@ type LeakyBucket struct {
 	leakInterval time.Duration // Интервал "протекания" одного токена (например, 100ms)
 	lastLeaked   time.Time
 	mu           sync.Mutex
+	clock        clock.Clock
+	changed      chan struct{} // закрывается при изменении параметров, чтобы разбудить ожидающих в Wait
 }
 
-func NewLeakyBucket(cap int, leakInterval time.Duration) *LeakyBucket {
+func NewLeakyBucket(cap int, leakInterval time.Duration, opts ...clock.Option) *LeakyBucket {
+	clk := clock.FromOptions(opts)
+
 	return &LeakyBucket{
 		cap:          cap,
 		leakInterval: leakInterval,
-		lastLeaked:   time.Now(),
+		lastLeaked:   clk.Now(),
+		clock:        clk,
+		changed:      make(chan struct{}),
 	}
 }
 
@@ -59,35 +66,131 @@ func (lb *LeakyBucket) Allow() bool {
 	return false
 }
 
-func (lb *LeakyBucket) Wait(ctx context.Context) {
-	lb.mu.Lock()
-	defer lb.mu.Unlock()
-
+// Wait блокирует выполнение до тех пор, пока в ведре не появится место.
+// Как и в TokenBucket, mutex на время ожидания не держим.
+func (lb *LeakyBucket) Wait(ctx context.Context) error {
 	for {
+		lb.mu.Lock()
+		lb.leak()
+
 		// проверка условия - Allow нельзя, он блокирующий
 		if lb.current < lb.cap {
 			lb.current++
-			return
+			lb.mu.Unlock()
+			return nil
 		}
 
-		timer := time.NewTimer(lb.leakInterval)
+		waiting := max(lb.leakInterval-lb.clock.Since(lb.lastLeaked), 0)
+		changed := lb.changed
+		lb.mu.Unlock()
+
+		timer := lb.clock.NewTimer(waiting)
 
 		select {
 		case <-ctx.Done():
 			timer.Stop()
-			return
-		case <-timer.C:
-			lb.leak()
+			return ctx.Err()
+		case <-timer.C():
+		case <-changed:
+			// параметры поменялись - пересчитываем время ожидания
+			timer.Stop()
 		}
 	}
 }
 
+// SetCapacity меняет ёмкость ведра на лету.
+// Если в ведре уже больше запросов, чем новая ёмкость, то новые не пропускаются, пока лишние не вытекут.
+func (lb *LeakyBucket) SetCapacity(cap int) {
+	if cap <= 0 {
+		panic("capacity must be greater than 0")
+	}
+
+	lb.Reconfigure(LimiterConfig{Burst: cap})
+}
+
+// SetLeakInterval меняет скорость протекания на лету.
+func (lb *LeakyBucket) SetLeakInterval(leakInterval time.Duration) {
+	if leakInterval <= 0 {
+		panic("leak interval must be greater than 0")
+	}
+
+	lb.Reconfigure(LimiterConfig{LeakInterval: leakInterval})
+}
+
+// Capacity возвращает текущую ёмкость ведра.
+func (lb *LeakyBucket) Capacity() int {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	return lb.cap
+}
+
+// LeakInterval возвращает текущий интервал протекания.
+func (lb *LeakyBucket) LeakInterval() time.Duration {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	return lb.leakInterval
+}
+
+// Reconfigure применяет ненулевые поля конфигурации.
+// Все поля меняются под одной блокировкой: Allow и State не увидят смесь старых и новых параметров.
+func (lb *LeakyBucket) Reconfigure(config LimiterConfig) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	// Сначала выпускаем то, что вытекло по старой скорости.
+	lb.leak()
+
+	if config.Burst > 0 {
+		lb.cap = config.Burst
+	}
+	if config.LeakInterval > 0 {
+		lb.leakInterval = config.LeakInterval
+	}
+
+	lb.notify()
+}
+
+// notify будит всех ожидающих в Wait. Вызывается под mutex.
+func (lb *LeakyBucket) notify() {
+	close(lb.changed)
+	lb.changed = make(chan struct{})
+}
+
+// State возвращает текущее состояние ведра.
+func (lb *LeakyBucket) State() State {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	lb.leak()
+
+	// после SetCapacity в ведре может оказаться больше запросов, чем новая ёмкость
+	st := State{
+		Limit:     lb.cap,
+		Remaining: max(lb.cap-lb.current, 0),
+	}
+
+	since := lb.clock.Since(lb.lastLeaked)
+
+	if lb.current > 0 {
+		st.Reset = max(time.Duration(lb.current)*lb.leakInterval-since, 0)
+	}
+
+	if lb.current >= lb.cap {
+		// чтобы появилось место, должны вытечь все лишние и еще один
+		st.RetryAfter = max(time.Duration(lb.current-lb.cap+1)*lb.leakInterval-since, 0)
+	}
+
+	return st
+}
+
 func (lb *LeakyBucket) leak() {
 	if lb.current == 0 {
 		return
 	}
 
-	elapsed := time.Now().Sub(lb.lastLeaked)
+	elapsed := lb.clock.Since(lb.lastLeaked)
 	leaked := int(elapsed / lb.leakInterval)
 
 	lb.current = max(lb.current-leaked, 0)
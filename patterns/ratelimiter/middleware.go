@@ -0,0 +1,132 @@
+package ratelimiter
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Middleware - net/http middleware, которое пропускает запросы через один общий лимитер.
+//
+// Требования:
+//   - при превышении лимита отвечает 429 Too Many Requests и заголовком Retry-After
+//   - если лимитер реализует Stater, то выставляет заголовки RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset
+//     (IETF draft "RateLimit header fields for HTTP")
+//   - с WithQueueTimeout не отклоняет запрос сразу, а ждет в очереди до указанного времени
+//
+// @idiomatic: func(http.Handler) http.Handler - совместимо с chi.Router.Use и обычным http.Handle
+func Middleware(limiter RateLimiter, opts ...MiddlewareOptionFunc) func(http.Handler) http.Handler {
+	return middleware(func(*http.Request) RateLimiter {
+		return limiter
+	}, opts)
+}
+
+// KeyedMiddleware - то же самое, но квота своя для каждого ключа, который извлекается из запроса keyFunc.
+func KeyedMiddleware(limiter *KeyedLimiter, keyFunc KeyFunc, opts ...MiddlewareOptionFunc) func(http.Handler) http.Handler {
+	return middleware(func(r *http.Request) RateLimiter {
+		return limiter.Get(keyFunc(r))
+	}, opts)
+}
+
+func middleware(resolve func(*http.Request) RateLimiter, opts []MiddlewareOptionFunc) func(http.Handler) http.Handler {
+	config := NewMiddlewareConfig(opts...)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limiter := resolve(r)
+
+			var allowed bool
+			if config.QueueTimeout > 0 {
+				// Ждем в очереди, но не дольше QueueTimeout и не дольше, чем живет сам запрос.
+				ctx, cancel := context.WithTimeout(r.Context(), config.QueueTimeout)
+				allowed = limiter.Wait(ctx) == nil
+				cancel()
+			} else {
+				allowed = limiter.Allow()
+			}
+
+			retryAfter := time.Second
+			if st, ok := limiter.(Stater); ok {
+				state := st.State()
+				writeRateLimitHeaders(w.Header(), state)
+				retryAfter = state.RetryAfter
+			}
+
+			if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(max(ceilSeconds(retryAfter), 1)))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeRateLimitHeaders(h http.Header, st State) {
+	h.Set("RateLimit-Limit", strconv.Itoa(st.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(st.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(st.Reset)))
+}
+
+// ceilSeconds - заголовки принимают целые секунды, округляем вверх, чтобы клиент не пришел раньше времени.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// KeyFunc извлекает из запроса ключ, по которому ведется учет.
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP - ключ по IP клиента (без порта).
+// X-Forwarded-For не учитывается намеренно: ему можно доверять только за своим proxy.
+func KeyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// KeyByHeader - ключ по значению заголовка (например, X-API-Key).
+func KeyByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// KeyByRoute - ключ по шаблону маршрута (/users/{id}), а не по конкретному URL, чтобы не плодить ключи.
+// Шаблон выставляет http.ServeMux, поэтому middleware должно оборачивать конкретный handler, а не весь mux.
+// Если шаблона нет, то используется путь.
+func KeyByRoute(r *http.Request) string {
+	if r.Pattern != "" {
+		return r.Pattern
+	}
+	return r.URL.Path
+}
+
+type MiddlewareConfig struct {
+	QueueTimeout time.Duration // 0 - отклонять сразу
+}
+
+func NewMiddlewareConfig(opts ...MiddlewareOptionFunc) *MiddlewareConfig {
+	config := &MiddlewareConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
+
+type MiddlewareOptionFunc func(*MiddlewareConfig)
+
+func WithQueueTimeout(val time.Duration) MiddlewareOptionFunc {
+	if val < 0 {
+		panic("queue timeout must be greater or equal than 0, pass 0 if you want to reject immediately")
+	}
+
+	return func(c *MiddlewareConfig) {
+		c.QueueTimeout = val
+	}
+}
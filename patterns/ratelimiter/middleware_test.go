@@ -0,0 +1,168 @@
+package ratelimiter
+
+import (
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	t.Run("rejects_with_headers", func(t *testing.T) {
+		handler := Middleware(NewTokenBucket(2, 1))(okHandler())
+
+		for i := 0; i < 2; i++ {
+			rec := serve(handler, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("got %v, want %v", rec.Code, http.StatusOK)
+			}
+		}
+
+		rec := serve(handler, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("got %v, want %v", rec.Code, http.StatusTooManyRequests)
+		}
+
+		want := map[string]string{
+			"Retry-After":         "1",
+			"RateLimit-Limit":     "2",
+			"RateLimit-Remaining": "0",
+			"RateLimit-Reset":     "2",
+		}
+		for name, val := range want {
+			if got := rec.Header().Get(name); got != val {
+				t.Errorf("%s: got %q, want %q", name, got, val)
+			}
+		}
+	})
+
+	t.Run("queue_waits", func(t *testing.T) {
+		handler := Middleware(NewTokenBucket(1, 20), WithQueueTimeout(200*time.Millisecond))(okHandler())
+
+		serve(handler, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		ts := time.Now()
+		rec := serve(handler, httptest.NewRequest(http.MethodGet, "/", nil))
+		elapsed := time.Since(ts)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got %v, want %v", rec.Code, http.StatusOK)
+		}
+
+		if elapsed < 40*time.Millisecond || elapsed > 100*time.Millisecond {
+			t.Errorf("got %v, want about 50ms", elapsed)
+		}
+	})
+
+	t.Run("queue_timeout", func(t *testing.T) {
+		handler := Middleware(NewLeakyBucket(1, time.Second), WithQueueTimeout(20*time.Millisecond))(okHandler())
+
+		serve(handler, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		rec := serve(handler, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("got %v, want %v", rec.Code, http.StatusTooManyRequests)
+		}
+	})
+
+	t.Run("keyed_by_header", func(t *testing.T) {
+		limiter := NewKeyedLimiter(func() RateLimiter {
+			return NewTokenBucket(1, 1)
+		})
+		handler := KeyedMiddleware(limiter, KeyByHeader("X-API-Key"))(okHandler())
+
+		codes := make([]int, 0, 3)
+		for _, key := range []string{"a", "b", "a"} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-API-Key", key)
+			codes = append(codes, serve(handler, req).Code)
+		}
+
+		want := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}
+		for i := range want {
+			if codes[i] != want[i] {
+				t.Errorf("request %d: got %v, want %v", i, codes[i], want[i])
+			}
+		}
+
+		if limiter.Len() != 2 {
+			t.Errorf("got %v keys, want 2", limiter.Len())
+		}
+	})
+
+	t.Run("keyed_by_route", func(t *testing.T) {
+		limiter := NewKeyedLimiter(func() RateLimiter {
+			return NewTokenBucket(1, 1)
+		})
+
+		mux := http.NewServeMux()
+		mux.Handle("/users/{id}", KeyedMiddleware(limiter, KeyByRoute)(okHandler()))
+
+		serve(mux, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+		rec := serve(mux, httptest.NewRequest(http.MethodGet, "/users/2", nil))
+
+		// разные URL, но один шаблон - одна квота
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("got %v, want %v", rec.Code, http.StatusTooManyRequests)
+		}
+	})
+}
+
+func TestKeyByIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:54321"
+
+	if got := KeyByIP(req); got != "10.0.0.1" {
+		t.Errorf("got %q, want %q", got, "10.0.0.1")
+	}
+}
+
+func TestKeyedLimiterJanitor(t *testing.T) {
+	limiter := NewKeyedLimiter(func() RateLimiter {
+		return NewTokenBucket(1, 1)
+	})
+	limiter.UseJanitor(t.Context(), 5*time.Millisecond, 10*time.Millisecond)
+
+	limiter.Allow("a")
+	time.Sleep(50 * time.Millisecond)
+
+	if limiter.Len() != 0 {
+		t.Errorf("got %v keys, want 0", limiter.Len())
+	}
+}
+
+func TestKeyedLimiterJanitorFakeClock(t *testing.T) {
+	c := clock.NewFake()
+	limiter := NewKeyedLimiter(func() RateLimiter {
+		return NewTokenBucket(1, 1, clock.With(c))
+	}, clock.With(c))
+	limiter.UseJanitor(t.Context(), time.Second, 10*time.Second)
+	c.BlockUntil(1)
+
+	limiter.Allow("a")
+	limiter.Allow("b")
+
+	c.Advance(5 * time.Second)
+	limiter.Allow("b")
+
+	// "a" простаивает 11s, "b" - 6s
+	c.Advance(6 * time.Second)
+	eventually(t, func() bool { return limiter.Len() == 1 })
+
+	if _, ok := limiter.limiters["b"]; !ok {
+		t.Error("got active key evicted")
+	}
+}
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func serve(h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
@@ -1,6 +1,9 @@
 package ratelimiter
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // RateLimiter
 //
@@ -47,5 +50,20 @@ type RateLimiter interface {
 	Allow() bool
 
 	// Wait блокирует выполнение до тех пор, пока не будет разрешено.
-	Wait(ctx context.Context)
+	// Возвращает ошибку контекста, если дождаться не удалось.
+	Wait(ctx context.Context) error
+}
+
+// Stater - лимитер, который умеет сообщать свое текущее состояние.
+// Нужен, например, для заголовков RateLimit-* в http middleware.
+type Stater interface {
+	State() State
+}
+
+// State - снимок состояния лимитера.
+type State struct {
+	Limit      int           // Максимальная квота
+	Remaining  int           // Сколько еще можно взять прямо сейчас
+	Reset      time.Duration // Через сколько квота восстановится полностью
+	RetryAfter time.Duration // Через сколько освободится хотя бы одно место (0 - если уже есть)
 }
@@ -2,6 +2,7 @@ package ratelimiter
 
 import (
 	"context"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
 	"testing"
 	"time"
 )
@@ -50,6 +51,18 @@ func TestLeakyBucket(t *testing.T) {
 	})
 }
 
+func TestFakeClock(t *testing.T) {
+	t.Run("token_bucket", func(t *testing.T) {
+		c := clock.NewFake()
+		fakeClockWait(t, c, NewTokenBucket(2, 1, clock.With(c)))
+	})
+
+	t.Run("leaky_bucket", func(t *testing.T) {
+		c := clock.NewFake()
+		fakeClockWait(t, c, NewLeakyBucket(2, time.Second, clock.With(c)))
+	})
+}
+
 func allow(t *testing.T, r RateLimiter) {
 	for i := 0; i < 10; i++ {
 		if !r.Allow() {
@@ -84,6 +97,45 @@ func wait(t *testing.T, r RateLimiter) {
 	t.Log("expected")
 }
 
+// fakeClockWait - то же, что wait, но без sleep: время двигаем вручную.
+func fakeClockWait(t *testing.T, c *clock.Fake, r RateLimiter) {
+	r.Allow()
+	r.Allow()
+
+	if r.Allow() {
+		t.Fatalf("must not be allowed")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Wait(t.Context())
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(999 * time.Millisecond)
+
+	select {
+	case <-done:
+		t.Fatalf("waited less than a second")
+	default:
+	}
+
+	c.Advance(time.Millisecond)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("got error %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("waiter was not woken")
+	}
+
+	if r.Allow() {
+		t.Errorf("must not be allowed")
+	}
+}
+
 func cancelable(t *testing.T, r RateLimiter) {
 	ctx, cancel := context.WithCancel(t.Context())
 
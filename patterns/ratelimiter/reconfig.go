@@ -0,0 +1,113 @@
+package ratelimiter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
+	"os"
+	"time"
+)
+
+// LimiterConfig - параметры лимитера, которые можно менять на лету. Нулевое поле - не менять.
+type LimiterConfig struct {
+	Rate         float64       // TokenBucket: скорость пополнения в секунду
+	Burst        int           // TokenBucket: ёмкость; LeakyBucket: ёмкость
+	LeakInterval time.Duration // LeakyBucket: интервал протекания
+}
+
+// Reconfigurable - лимитер, параметры которого можно менять без пересоздания (и без потери состояния).
+type Reconfigurable interface {
+	Reconfigure(config LimiterConfig)
+}
+
+// ConfigSource - откуда брать актуальную конфигурацию (файл, env, consul и т.д.).
+type ConfigSource func(ctx context.Context) (LimiterConfig, error)
+
+// FileConfigSource читает конфигурацию из JSON-файла вида:
+//
+//	{"rate": 10, "burst": 20, "leak_interval": "100ms"}
+func FileConfigSource(path string) ConfigSource {
+	return func(ctx context.Context) (LimiterConfig, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return LimiterConfig{}, err
+		}
+
+		// time.Duration в JSON - это наносекунды, человеку удобнее строка "100ms".
+		var raw struct {
+			Rate         float64 `json:"rate"`
+			Burst        int     `json:"burst"`
+			LeakInterval string  `json:"leak_interval"`
+		}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return LimiterConfig{}, fmt.Errorf("parse %s: %w", path, err)
+		}
+
+		config := LimiterConfig{
+			Rate:  raw.Rate,
+			Burst: raw.Burst,
+		}
+
+		if raw.LeakInterval != "" {
+			config.LeakInterval, err = time.ParseDuration(raw.LeakInterval)
+			if err != nil {
+				return LimiterConfig{}, fmt.Errorf("parse %s: %w", path, err)
+			}
+		}
+
+		if config.Rate < 0 || config.Burst < 0 || config.LeakInterval < 0 {
+			return LimiterConfig{}, fmt.Errorf("parse %s: negative values are not allowed", path)
+		}
+
+		return config, nil
+	}
+}
+
+// WatchConfig сразу и затем раз в interval читает source и применяет конфигурацию к limiter, если она изменилась.
+// Ошибки чтения передаются в onError (может быть nil), при ошибке остается последняя примененная конфигурация.
+// Останавливается при отмене ctx.
+func WatchConfig(ctx context.Context, limiter Reconfigurable, source ConfigSource, interval time.Duration, onError func(error), opts ...clock.Option) {
+	// проверяем здесь, а не в goroutine: паника из NewTicker там уронила бы процесс, а не вызывающего
+	if interval <= 0 {
+		panic("interval must be greater than 0")
+	}
+
+	clk := clock.FromOptions(opts)
+
+	go func() {
+		var last LimiterConfig
+
+		apply := func() {
+			config, err := source(ctx)
+			if err != nil {
+				if onError != nil {
+					onError(err)
+				}
+				return
+			}
+
+			// @idiomatic: comparable struct
+			if config == last {
+				return
+			}
+
+			limiter.Reconfigure(config)
+			last = config
+		}
+
+		apply()
+
+		ticker := clk.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				apply()
+			}
+		}
+	}()
+}
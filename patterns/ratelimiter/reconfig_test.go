@@ -0,0 +1,201 @@
+package ratelimiter
+
+import (
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketReconfigure(t *testing.T) {
+	t.Run("set_rate_wakes_waiter", func(t *testing.T) {
+		c := clock.NewFake()
+		r := NewTokenBucket(1, 1, clock.With(c))
+		r.Allow()
+
+		done := waitAsync(t, r)
+		c.BlockUntil(1)
+
+		// со старой скоростью ждать секунду, с новой - 100ms
+		r.SetRate(10)
+		c.Advance(100 * time.Millisecond)
+
+		mustBeDone(t, done)
+	})
+
+	t.Run("set_burst_drops_extra_tokens", func(t *testing.T) {
+		r := NewTokenBucket(10, 1)
+		r.SetBurst(2)
+
+		if got := allowedCount(10, r); got != 2 {
+			t.Errorf("got %v allowed, want 2", got)
+		}
+
+		if r.Burst() != 2 {
+			t.Errorf("got %v, want 2", r.Burst())
+		}
+	})
+
+	t.Run("keeps_state", func(t *testing.T) {
+		r := NewTokenBucket(5, 1)
+		allowedCount(3, r)
+
+		r.SetRate(100)
+
+		// 2 токена остались, ведро не пересоздано
+		if got := allowedCount(10, r); got != 2 {
+			t.Errorf("got %v allowed, want 2", got)
+		}
+	})
+}
+
+func TestLeakyBucketReconfigure(t *testing.T) {
+	t.Run("set_leak_interval_wakes_waiter", func(t *testing.T) {
+		c := clock.NewFake()
+		r := NewLeakyBucket(1, time.Second, clock.With(c))
+		r.Allow()
+
+		done := waitAsync(t, r)
+		c.BlockUntil(1)
+
+		r.SetLeakInterval(100 * time.Millisecond)
+		c.Advance(100 * time.Millisecond)
+
+		mustBeDone(t, done)
+	})
+
+	t.Run("set_capacity_wakes_waiter", func(t *testing.T) {
+		c := clock.NewFake()
+		r := NewLeakyBucket(1, time.Second, clock.With(c))
+		r.Allow()
+
+		done := waitAsync(t, r)
+		c.BlockUntil(1)
+
+		// место появляется сразу, время не двигаем
+		r.SetCapacity(2)
+
+		mustBeDone(t, done)
+	})
+
+	t.Run("shrink_capacity_under_load", func(t *testing.T) {
+		c := clock.NewFake()
+		r := NewLeakyBucket(5, time.Second, clock.With(c))
+		allowedCount(5, r)
+
+		r.SetCapacity(2)
+
+		st := r.State()
+		if st.Limit != 2 || st.Remaining != 0 {
+			t.Errorf("got limit=%v remaining=%v, want limit=2 remaining=0", st.Limit, st.Remaining)
+		}
+		// место появится, когда из 5 останется 1: вытечь должны 4
+		if st.RetryAfter != 4*time.Second {
+			t.Errorf("got retry after %v, want 4s", st.RetryAfter)
+		}
+
+		c.Advance(3 * time.Second)
+		if r.Allow() {
+			t.Fatal("got allowed while bucket is still over capacity")
+		}
+
+		c.Advance(time.Second)
+		if !r.Allow() {
+			t.Fatal("got not allowed after extra requests leaked")
+		}
+	})
+}
+
+func TestWatchConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	writeFile(t, path, `{"rate": 5, "burst": 3}`)
+
+	c := clock.NewFake()
+	r := NewTokenBucket(1, 1)
+
+	var errs atomic.Int32
+	WatchConfig(t.Context(), r, FileConfigSource(path), time.Second, func(err error) {
+		errs.Add(1)
+	}, clock.With(c))
+
+	// применяется сразу, затем создается ticker
+	c.BlockUntil(1)
+	if r.Rate() != 5 || r.Burst() != 3 {
+		t.Fatalf("got rate=%v burst=%v, want rate=5 burst=3", r.Rate(), r.Burst())
+	}
+
+	writeFile(t, path, `{"rate": 5, "burst": 7}`)
+	c.Advance(time.Second)
+	eventually(t, func() bool { return r.Burst() == 7 })
+
+	// битый файл - остается последняя конфигурация
+	writeFile(t, path, `{"rate": `)
+	c.Advance(time.Second)
+	eventually(t, func() bool { return errs.Load() == 1 })
+
+	if r.Rate() != 5 || r.Burst() != 7 {
+		t.Errorf("got rate=%v burst=%v, want rate=5 burst=7", r.Rate(), r.Burst())
+	}
+}
+
+func TestWatchConfigInvalidInterval(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("got no panic")
+		}
+	}()
+
+	WatchConfig(t.Context(), NewTokenBucket(1, 1), FileConfigSource("limits.json"), 0, nil)
+}
+
+func TestFileConfigSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	writeFile(t, path, `{"burst": 3, "leak_interval": "250ms"}`)
+
+	config, err := FileConfigSource(path)(t.Context())
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+
+	want := LimiterConfig{Burst: 3, LeakInterval: 250 * time.Millisecond}
+	if config != want {
+		t.Errorf("got %+v, want %+v", config, want)
+	}
+}
+
+func waitAsync(t *testing.T, r RateLimiter) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Wait(t.Context())
+	}()
+	return done
+}
+
+func mustBeDone(t *testing.T, done <-chan error) {
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("got error %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("waiter was not woken")
+	}
+}
+
+func eventually(t *testing.T, cond func() bool) {
+	for range 1000 {
+		if cond() {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("condition was not met")
+}
+
+func writeFile(t *testing.T, path string, data string) {
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
@@ -0,0 +1,89 @@
+package ratelimiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store - общее для всех реплик атомарное хранилище (Redis, memcached, etcd и т.д.).
+//
+// Требования к реализации:
+//   - все операции атомарны относительно друг друга
+//   - отсутствующий (или истекший) ключ читается как 0
+type Store interface {
+	// Get возвращает текущее значение ключа.
+	Get(ctx context.Context, key string) (int64, error)
+
+	// Incr атомарно прибавляет delta и возвращает новое значение.
+	// Если ключа не было, то он создается со временем жизни ttl (аналог INCRBY + EXPIRE NX в Redis).
+	Incr(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+
+	// CompareAndSet атомарно заменяет значение old на new со временем жизни ttl.
+	// Возвращает false, если текущее значение уже не old.
+	CompareAndSet(ctx context.Context, key string, old, new int64, ttl time.Duration) (bool, error)
+}
+
+// MemoryStore - in-memory реализация Store для тестов и single-node использования.
+type MemoryStore struct {
+	items map[string]memoryItem
+	mu    sync.Mutex
+}
+
+type memoryItem struct {
+	value  int64
+	expire time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		items: make(map[string]memoryItem),
+	}
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, _ := s.get(key)
+	return item.value, nil
+}
+
+func (s *MemoryStore) Incr(_ context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.get(key)
+	if !ok {
+		item.expire = time.Now().Add(ttl)
+	}
+	item.value += delta
+
+	s.items[key] = item
+
+	return item.value, nil
+}
+
+func (s *MemoryStore) CompareAndSet(_ context.Context, key string, old, new int64, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, _ := s.get(key)
+	if item.value != old {
+		return false, nil
+	}
+
+	s.items[key] = memoryItem{value: new, expire: time.Now().Add(ttl)}
+
+	return true, nil
+}
+
+// get возвращает живой элемент, истекшие удаляет (lazy cleaning).
+func (s *MemoryStore) get(key string) (memoryItem, bool) {
+	item, ok := s.items[key]
+	if ok && !item.expire.After(time.Now()) {
+		delete(s.items, key)
+		return memoryItem{}, false
+	}
+	return item, ok
+}
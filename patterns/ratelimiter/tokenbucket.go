@@ -2,6 +2,7 @@ package ratelimiter
 
 import (
 	"context"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
 	"math"
 	"sync"
 	"time"
@@ -36,11 +37,15 @@ type TokenBucket struct {
 	lastRefilled  time.Time
 	mu            sync.Mutex
 	remainder     float64 // Остаток от целой части прибавленной в прошлый раз (micro-drift fix)
+	clock         clock.Clock
+	changed       chan struct{} // закрывается при изменении параметров, чтобы разбудить ожидающих в Wait
 }
 
 // NewTokenBucket создает новую структуру.
 // @idiomatic: store mutex in struct
-func NewTokenBucket(cap int, refillRate float64) *TokenBucket {
+func NewTokenBucket(cap int, refillRate float64, opts ...clock.Option) *TokenBucket {
+	clk := clock.FromOptions(opts)
+
 	// Создать mutex и передать его при создании структуры нельзя. Получим ошибку:
 	// "Literal copies a lock value from 'mu': type 'sync.Mutex' is 'sync.Locker'"
 	// Потому что даже при этом производится копирование (а mutex нельзя копировать _noCopy).
@@ -56,7 +61,9 @@ func NewTokenBucket(cap int, refillRate float64) *TokenBucket {
 		cap:           cap,
 		tokens:        float64(cap), // наполненное со старта
 		secRefillRate: refillRate,
-		lastRefilled:  time.Now(),
+		lastRefilled:  clk.Now(),
+		clock:         clk,
+		changed:       make(chan struct{}),
 	}
 
 	// решил обойтись без cond, так как его использование требует запуска goroutine для refill
@@ -82,37 +89,136 @@ func (tb *TokenBucket) Allow() bool {
 }
 
 // Wait блокирует выполнение до тех пор, пока не появится токен.
+// Mutex на время ожидания отпускаем: иначе все остальные (в том числе Allow) ждали бы вместе с нами
+// и не могли бы отреагировать на свой контекст.
 // @idiomatic: float duration in ns
 // @idiomatic: cancel timer, do not call defer timer.Stop() in loop
-func (tb *TokenBucket) Wait(ctx context.Context) {
-	tb.mu.Lock()
-	defer tb.mu.Unlock()
-
+func (tb *TokenBucket) Wait(ctx context.Context) error {
 	for {
+		tb.mu.Lock()
+		tb.refill()
+
 		// проверка условия, аllow нельзя, он блокирующий
 		if tb.tokens >= 1 {
 			tb.tokens -= 1
-			return
+			tb.mu.Unlock()
+			return nil
 		}
 
-		waiting := time.Duration(((1 - tb.tokens) / tb.secRefillRate) * float64(time.Second))
-		timer := time.NewTimer(waiting)
+		waiting := tb.durationFor(1 - tb.tokens - tb.remainder)
+		changed := tb.changed
+		tb.mu.Unlock()
+
+		timer := tb.clock.NewTimer(waiting)
 		// defer timer.Stop() - здесь нельзя, потому что цикл.
 
 		select {
 		case <-ctx.Done():
 			timer.Stop()
-			return
-		case <-timer.C:
-			tb.refill()
+			return ctx.Err()
+		case <-timer.C():
 			// таймер уже отработал, stop не обязателен
 			// timer.Stop()
+		case <-changed:
+			// параметры поменялись - пересчитываем время ожидания
+			timer.Stop()
 		}
 	}
 }
 
+// SetRate меняет скорость пополнения на лету, накопленные токены сохраняются.
+func (tb *TokenBucket) SetRate(rate float64) {
+	if rate <= 0 {
+		panic("rate must be greater than 0")
+	}
+
+	tb.Reconfigure(LimiterConfig{Rate: rate})
+}
+
+// SetBurst меняет ёмкость ведра на лету, лишние токены сгорают.
+func (tb *TokenBucket) SetBurst(burst int) {
+	if burst <= 0 {
+		panic("burst must be greater than 0")
+	}
+
+	tb.Reconfigure(LimiterConfig{Burst: burst})
+}
+
+// Rate возвращает текущую скорость пополнения.
+func (tb *TokenBucket) Rate() float64 {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	return tb.secRefillRate
+}
+
+// Burst возвращает текущую ёмкость ведра.
+func (tb *TokenBucket) Burst() int {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	return tb.cap
+}
+
+// Reconfigure применяет ненулевые поля конфигурации.
+// Все поля меняются под одной блокировкой: Allow и State не увидят смесь старых и новых параметров.
+func (tb *TokenBucket) Reconfigure(config LimiterConfig) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	// Сначала начисляем то, что накопилось по старой скорости.
+	tb.refill()
+
+	if config.Rate > 0 {
+		tb.secRefillRate = config.Rate
+	}
+	if config.Burst > 0 {
+		// лишние токены сгорают
+		tb.cap = config.Burst
+		tb.tokens = math.Min(tb.tokens, float64(config.Burst))
+	}
+
+	tb.notify()
+}
+
+// notify будит всех ожидающих в Wait: закрытие канала - broadcast для любого количества читателей.
+// Вызывается под mutex.
+// @idiomatic: close channel as broadcast
+func (tb *TokenBucket) notify() {
+	close(tb.changed)
+	tb.changed = make(chan struct{})
+}
+
+// State возвращает текущее состояние ведра.
+func (tb *TokenBucket) State() State {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.refill()
+
+	st := State{
+		Limit:     tb.cap,
+		Remaining: int(tb.tokens),
+	}
+
+	if missing := float64(tb.cap) - tb.tokens - tb.remainder; missing > 0 {
+		st.Reset = tb.durationFor(missing)
+	}
+
+	if tb.tokens < 1 {
+		st.RetryAfter = tb.durationFor(1 - tb.tokens - tb.remainder)
+	}
+
+	return st
+}
+
+// durationFor - за сколько накопится указанное количество токенов.
+func (tb *TokenBucket) durationFor(tokens float64) time.Duration {
+	return time.Duration(tokens / tb.secRefillRate * float64(time.Second))
+}
+
 func (tb *TokenBucket) refill() {
-	now := time.Now()
+	now := tb.clock.Now()
 
 	// В float64 не точно будут представлены маленькие длительности вида 3ms.
 	// Реально прошло 0.0378123 сек, округлилось до 0.0378125. На каждый refill +0.0000002 ошибки.
@@ -0,0 +1,120 @@
+package retry
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff - стратегия вычисления задержки между попытками.
+//
+// Зачем jitter:
+// Если много клиентов упали одновременно (например, downstream перезапустился), то без случайности
+// они и повторять будут одновременно - волнами (thundering herd). Jitter размазывает повторы по времени.
+// Подробно: https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+type Backoff interface {
+	// Delay возвращает задержку после неудачной попытки attempt (начиная с 0).
+	// prev - предыдущая задержка (0 для первой), нужна стратегиям с состоянием, например Decorrelated.
+	Delay(attempt int, prev time.Duration) time.Duration
+}
+
+// BackoffFunc - функция как Backoff.
+// @idiomatic: func type implementing interface (как http.HandlerFunc)
+type BackoffFunc func(attempt int, prev time.Duration) time.Duration
+
+func (f BackoffFunc) Delay(attempt int, prev time.Duration) time.Duration {
+	return f(attempt, prev)
+}
+
+// Constant - одинаковая задержка между всеми попытками.
+func Constant(d time.Duration) Backoff {
+	return BackoffFunc(func(int, time.Duration) time.Duration {
+		return d
+	})
+}
+
+// Exponential - base * factor^attempt, без jitter.
+func Exponential(base time.Duration, factor float64) Backoff {
+	return BackoffFunc(func(attempt int, _ time.Duration) time.Duration {
+		return saturate(float64(base) * math.Pow(factor, float64(attempt)))
+	})
+}
+
+// FullJitter - случайная задержка от 0 до значения b: random(0, d).
+// Лучше всех размазывает нагрузку, но иногда повторяет почти сразу.
+func FullJitter(b Backoff) Backoff {
+	return BackoffFunc(func(attempt int, prev time.Duration) time.Duration {
+		d := b.Delay(attempt, prev)
+		if d <= 0 {
+			return 0
+		}
+		return time.Duration(rand.Int63n(int64(d)))
+	})
+}
+
+// EqualJitter - половина задержки гарантирована, вторая половина случайна: d/2 + random(0, d/2).
+func EqualJitter(b Backoff) Backoff {
+	return BackoffFunc(func(attempt int, prev time.Duration) time.Duration {
+		half := b.Delay(attempt, prev) / 2
+		if half <= 0 {
+			return 0
+		}
+		return half + time.Duration(rand.Int63n(int64(half)))
+	})
+}
+
+// Decorrelated - random(base, prev*3), не больше maxDelay.
+// Каждая задержка зависит от предыдущей, а не от номера попытки, поэтому клиенты быстро "расходятся".
+func Decorrelated(base time.Duration, maxDelay time.Duration) Backoff {
+	return BackoffFunc(func(_ int, prev time.Duration) time.Duration {
+		upper := max(prev*3, base)
+		d := base
+		if upper > base {
+			d += time.Duration(rand.Int63n(int64(upper - base)))
+		}
+		return min(d, maxDelay)
+	})
+}
+
+// Fibonacci - base * fib(attempt+1): 1, 1, 2, 3, 5, 8...
+// Растет медленнее экспоненты, но быстрее константы.
+func Fibonacci(base time.Duration) Backoff {
+	return BackoffFunc(func(attempt int, _ time.Duration) time.Duration {
+		a, b := 0.0, 1.0
+		for range attempt {
+			a, b = b, a+b
+		}
+		return saturate(float64(base) * b)
+	})
+}
+
+// Schedule - заранее заданный список задержек. Когда список закончился, повторяется последняя.
+func Schedule(delays ...time.Duration) Backoff {
+	if len(delays) == 0 {
+		panic("schedule must contain at least one delay")
+	}
+
+	return BackoffFunc(func(attempt int, _ time.Duration) time.Duration {
+		return delays[min(attempt, len(delays)-1)]
+	})
+}
+
+// configBackoff - исходная формула: Delay * BackoffFactor^attempt + пропорциональный jitter до JitterFactor.
+func configBackoff(config *Config) Backoff {
+	return BackoffFunc(func(attempt int, _ time.Duration) time.Duration {
+		// @idiomatic: convert time.Duration to int64/float64 returns nanoseconds
+		d := float64(config.Delay) * math.Pow(config.BackoffFactor, float64(attempt))
+		d += d * rand.Float64() * config.JitterFactor
+
+		// @idiomatic: type casting to time.Duration accepts nanoseconds
+		return saturate(d)
+	})
+}
+
+// saturate - экспонента быстро выходит за пределы int64, при переполнении конвертация дает мусор.
+func saturate(d float64) time.Duration {
+	if d >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(d)
+}
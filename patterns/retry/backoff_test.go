@@ -0,0 +1,75 @@
+package retry
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	delays := func(b Backoff, n int) []time.Duration {
+		var res []time.Duration
+		var prev time.Duration
+		for attempt := range n {
+			prev = b.Delay(attempt, prev)
+			res = append(res, prev)
+		}
+		return res
+	}
+
+	ms := time.Millisecond
+
+	t.Run("deterministic", func(t *testing.T) {
+		cases := []struct {
+			name    string
+			backoff Backoff
+			want    []time.Duration
+		}{
+			{"constant", Constant(10 * ms), []time.Duration{10 * ms, 10 * ms, 10 * ms}},
+			{"exponential", Exponential(10*ms, 2), []time.Duration{10 * ms, 20 * ms, 40 * ms, 80 * ms}},
+			{"fibonacci", Fibonacci(10 * ms), []time.Duration{10 * ms, 10 * ms, 20 * ms, 30 * ms, 50 * ms, 80 * ms}},
+			{"schedule", Schedule(1*ms, 5*ms, 7*ms), []time.Duration{1 * ms, 5 * ms, 7 * ms, 7 * ms}},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				if got := delays(tc.backoff, len(tc.want)); !slices.Equal(got, tc.want) {
+					t.Errorf("got %v, want %v", got, tc.want)
+				}
+			})
+		}
+	})
+
+	t.Run("exponential_saturates", func(t *testing.T) {
+		if got := Exponential(time.Second, 10).Delay(100, 0); got <= 0 {
+			t.Errorf("got %v, want positive", got)
+		}
+	})
+
+	t.Run("jitter_bounds", func(t *testing.T) {
+		base := Constant(100 * ms)
+
+		for range 1000 {
+			if d := FullJitter(base).Delay(0, 0); d < 0 || d >= 100*ms {
+				t.Fatalf("full jitter: got %v, want [0, 100ms)", d)
+			}
+
+			if d := EqualJitter(base).Delay(0, 0); d < 50*ms || d >= 100*ms {
+				t.Fatalf("equal jitter: got %v, want [50ms, 100ms)", d)
+			}
+		}
+	})
+
+	t.Run("decorrelated_bounds", func(t *testing.T) {
+		b := Decorrelated(10*ms, 200*ms)
+
+		var prev time.Duration
+		for attempt := range 1000 {
+			d := b.Delay(attempt, prev)
+			if d < 10*ms || d > 200*ms || d > max(prev*3, 10*ms) {
+				t.Fatalf("got %v after %v, want [10ms, min(200ms, prev*3)]", d, prev)
+			}
+			prev = d
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package retry
+
+import (
+	"errors"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
+	"sync"
+	"time"
+)
+
+// ErrRetryBudgetExhausted - повтор не выполнен, потому что бюджет повторов исчерпан.
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// RetryBudget - общий на всех вызывающих бюджет повторов (retry storm protection).
+//
+// Проблема:
+// Когда зависимость падает, каждый вызов делает MaxAttempts попыток, то есть нагрузка на и так лежащий сервис
+// умножается на MaxAttempts. Он не может подняться, потому что его добивают повторами.
+//
+// Идея (как в Finagle и gRPC retry throttling):
+// Это token bucket, только токены дают не часы, а успешные вызовы. Каждый успешный вызов добавляет ratio токенов,
+// каждый повтор забирает 1 токен. При ratio = 0.1 повторов не больше 10% от успешных вызовов.
+// Чтобы при малом трафике повторы вообще были возможны, дополнительно токены пополняются со скоростью
+// minPerSecond в секунду.
+//
+// Первые попытки бюджет не ограничивает, только повторы.
+type RetryBudget struct {
+	ratio        float64 // сколько токенов дает один успешный вызов
+	minPerSecond float64 // сколько токенов добавляется в секунду независимо от успехов
+	cap          float64
+	tokens       float64
+	lastRefilled time.Time
+	clock        clock.Clock
+	mu           sync.Mutex
+}
+
+// NewRetryBudget создает бюджет, ёмкость которого - 10 секунд повторов с минимальной скоростью (но не меньше 10),
+// чтобы накопленные во время нормальной работы токены не позволили устроить шторм при падении.
+func NewRetryBudget(ratio float64, minPerSecond float64, opts ...BudgetOptionFunc) *RetryBudget {
+	if ratio < 0 || minPerSecond < 0 {
+		panic("ratio and min per second must be greater or equal than 0")
+	}
+
+	o := budgetOptions{clock: clock.Real}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	cap := o.cap
+	if cap <= 0 {
+		cap = max(10*minPerSecond, 10)
+	}
+
+	return &RetryBudget{
+		ratio:        ratio,
+		minPerSecond: minPerSecond,
+		cap:          cap,
+		tokens:       cap, // наполненное со старта, как и TokenBucket
+		lastRefilled: o.clock.Now(),
+		clock:        o.clock,
+	}
+}
+
+// Deposit учитывает успешный вызов.
+func (b *RetryBudget) Deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	b.tokens = min(b.tokens+b.ratio, b.cap)
+}
+
+// TryWithdraw забирает токен на один повтор, false - бюджет исчерпан.
+func (b *RetryBudget) TryWithdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+
+	if b.tokens >= 1 {
+		b.tokens -= 1
+		return true
+	}
+
+	return false
+}
+
+// Available возвращает количество доступных сейчас повторов.
+func (b *RetryBudget) Available() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	return int(b.tokens)
+}
+
+func (b *RetryBudget) refill() {
+	now := b.clock.Now()
+	elapsed := now.Sub(b.lastRefilled).Seconds()
+	b.lastRefilled = now
+
+	b.tokens = min(b.tokens+elapsed*b.minPerSecond, b.cap)
+}
+
+type budgetOptions struct {
+	clock clock.Clock
+	cap   float64
+}
+
+type BudgetOptionFunc func(*budgetOptions)
+
+func WithBudgetClock(val clock.Clock) BudgetOptionFunc {
+	return func(o *budgetOptions) {
+		o.clock = clock.OrReal(val)
+	}
+}
+
+// WithBudgetCap задает максимальное количество накопленных повторов.
+func WithBudgetCap(val int) BudgetOptionFunc {
+	if val <= 0 {
+		panic("cap must be greater than 0")
+	}
+
+	return func(o *budgetOptions) {
+		o.cap = float64(val)
+	}
+}
@@ -0,0 +1,70 @@
+package retry
+
+import (
+	"errors"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
+	"testing"
+	"time"
+)
+
+func TestRetryBudget(t *testing.T) {
+	t.Run("ratio_of_successes", func(t *testing.T) {
+		b := NewRetryBudget(0.5, 0, WithBudgetCap(2))
+
+		for range 2 {
+			if !b.TryWithdraw() {
+				t.Fatalf("want initial tokens")
+			}
+		}
+
+		if b.TryWithdraw() {
+			t.Fatalf("want exhausted")
+		}
+
+		// 2 успеха = 1 повтор
+		b.Deposit()
+		b.Deposit()
+		if !b.TryWithdraw() || b.TryWithdraw() {
+			t.Fatalf("want exactly 1 retry")
+		}
+	})
+
+	t.Run("min_per_second", func(t *testing.T) {
+		c := clock.NewFake()
+		b := NewRetryBudget(0, 2, WithBudgetCap(1), WithBudgetClock(c))
+		b.TryWithdraw()
+
+		c.Advance(500 * time.Millisecond)
+		if !b.TryWithdraw() {
+			t.Fatalf("want refilled")
+		}
+
+		c.Advance(time.Hour)
+		if got := b.Available(); got != 1 {
+			t.Errorf("got %v, want capped 1", got)
+		}
+	})
+
+	t.Run("retry_fails_fast", func(t *testing.T) {
+		b := NewRetryBudget(0, 0, WithBudgetCap(1))
+		config := NewConfig(WithMaxAttempts(5), WithBackoff(Constant(0)), WithRetryBudget(b))
+
+		var retries int
+		fn := func() (int, error) {
+			retries++
+			return 0, errors.New("some error")
+		}
+
+		// первый вызов тратит единственный токен
+		_, err := Retry[int](t.Context(), fn, config)
+		if retries != 2 || !errors.Is(err, ErrRetryBudgetExhausted) {
+			t.Fatalf("got %v retries and %v, want 2 retries and budget error", retries, err)
+		}
+
+		retries = 0
+		_, err = Retry[int](t.Context(), fn, config)
+		if retries != 1 || !errors.Is(err, ErrRetryBudgetExhausted) {
+			t.Fatalf("got %v retries and %v, want 1 retry and budget error", retries, err)
+		}
+	})
+}
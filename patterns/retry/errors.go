@@ -0,0 +1,49 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RetryError - ошибка Retry: ошибки всех попыток и их количество.
+// errors.Is/errors.As работают с любой из ошибок попыток (и с ошибкой контекста, если Retry был отменен).
+type RetryError struct {
+	Attempts int
+	Err      error // errors.Join ошибок всех попыток
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("retry: %d attempts failed: %v", e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// PermanentError - ошибка, после которой повторять бессмысленно (например, 400 или ошибка валидации).
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent оборачивает err так, что Retry прекращает повторы независимо от RetryableChecker.
+// Для nil возвращает nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent сообщает, помечена ли err (или любая ошибка в ее цепочке) через Permanent.
+func IsPermanent(err error) bool {
+	var p *PermanentError
+	return errors.As(err, &p)
+}
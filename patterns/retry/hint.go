@@ -0,0 +1,85 @@
+package retry
+
+import (
+	"errors"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DelayHinter - ошибка, которая сама знает, сколько ждать до повтора (HTTP Retry-After, gRPC RetryInfo, Kafka throttle time).
+// Если DelayHint возвращает > 0, то Retry ждет именно столько (не больше MaxDelay) вместо задержки Backoff.
+type DelayHinter interface {
+	DelayHint() time.Duration
+}
+
+// delayHint - подсказка из цепочки ошибок, 0 - подсказки нет.
+func delayHint(err error) time.Duration {
+	var h DelayHinter
+	if errors.As(err, &h) {
+		return max(h.DelayHint(), 0)
+	}
+	return 0
+}
+
+// WithDelayHint прикрепляет к err подсказку о задержке, например, полученную через RetryAfter.
+// Для nil возвращает nil.
+func WithDelayHint(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &hintedError{err: err, delay: d}
+}
+
+type hintedError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *hintedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *hintedError) Unwrap() error {
+	return e.err
+}
+
+func (e *hintedError) DelayHint() time.Duration {
+	return e.delay
+}
+
+// RetryAfter извлекает задержку из заголовка Retry-After ответа (обычно при 429 и 503).
+// Заголовок бывает двух видов: количество секунд ("120") или HTTP-дата ("Wed, 21 Oct 2015 07:28:00 GMT").
+// Возвращает 0, если заголовка нет, он некорректен или дата уже прошла.
+// HTTP-дата отсчитывается от реального времени, с подменным Clock нужен Config.RetryAfter.
+func RetryAfter(resp *http.Response) time.Duration {
+	return retryAfter(resp, clock.Real)
+}
+
+// RetryAfter - как пакетная RetryAfter, но HTTP-дата отсчитывается от Clock конфигурации.
+func (c *Config) RetryAfter(resp *http.Response) time.Duration {
+	return retryAfter(resp, clock.OrReal(c.Clock))
+}
+
+func retryAfter(resp *http.Response, clk clock.Clock) time.Duration {
+	if resp == nil {
+		return 0
+	}
+
+	v := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if v == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+
+	if at, err := http.ParseTime(v); err == nil {
+		return max(at.Sub(clk.Now()), 0)
+	}
+
+	return 0
+}
@@ -0,0 +1,100 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	cases := []struct {
+		name   string
+		header string
+		min    time.Duration
+		max    time.Duration
+	}{
+		{"missing", "", 0, 0},
+		{"seconds", "120", 120 * time.Second, 120 * time.Second},
+		{"negative", "-5", 0, 0},
+		{"invalid", "soon", 0, 0},
+		{"date", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), 58 * time.Second, time.Minute},
+		{"past_date", "Wed, 21 Oct 2015 07:28:00 GMT", 0, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tc.header != "" {
+				resp.Header.Set("Retry-After", tc.header)
+			}
+
+			if got := RetryAfter(resp); got < tc.min || got > tc.max {
+				t.Errorf("got %v, want [%v, %v]", got, tc.min, tc.max)
+			}
+		})
+	}
+}
+
+func TestConfigRetryAfter(t *testing.T) {
+	c := clock.NewFakeAt(time.Date(2015, 10, 21, 7, 27, 0, 0, time.UTC))
+	config := NewConfig(WithClock(c))
+
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Retry-After", "Wed, 21 Oct 2015 07:28:00 GMT")
+
+	if got := config.RetryAfter(resp); got != time.Minute {
+		t.Errorf("got %v, want 1m", got)
+	}
+
+	c.Advance(2 * time.Minute)
+	if got := config.RetryAfter(resp); got != 0 {
+		t.Errorf("got %v, want 0 for past date", got)
+	}
+}
+
+func TestDelayHint(t *testing.T) {
+	run := func(hint time.Duration, maxDelay time.Duration) time.Duration {
+		c := clock.NewFake()
+		var delay time.Duration
+
+		config := NewConfig(
+			WithMaxAttempts(2),
+			WithBackoff(Constant(time.Second)),
+			WithMaxDelay(maxDelay),
+			WithClock(c),
+			WithOnRetry(func(_ context.Context, _ int, _ error, nextDelay time.Duration) {
+				delay = nextDelay
+			}),
+		)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = Retry[int](t.Context(), func() (int, error) {
+				return 0, fmt.Errorf("wrapped: %w", WithDelayHint(errors.New("throttled"), hint))
+			}, config)
+		}()
+
+		c.BlockUntil(1)
+		c.Advance(time.Hour)
+		<-done
+
+		return delay
+	}
+
+	if got := run(5*time.Second, time.Minute); got != 5*time.Second {
+		t.Errorf("got %v, want hinted 5s", got)
+	}
+
+	if got := run(5*time.Second, 2*time.Second); got != 2*time.Second {
+		t.Errorf("got %v, want capped 2s", got)
+	}
+
+	if got := run(0, time.Minute); got != time.Second {
+		t.Errorf("got %v, want backoff 1s", got)
+	}
+}
@@ -0,0 +1,91 @@
+package retry
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+// Metrics - prometheus-метрики Retry с label "operation" (название повторяемой операции).
+//
+// Cardinality: operation должен быть фиксированным набором строк ("get_user", "send_email"), а не id или URL.
+type Metrics struct {
+	retries  *prometheus.CounterVec
+	giveUps  *prometheus.CounterVec
+	delays   *prometheus.HistogramVec
+	attempts *prometheus.HistogramVec
+}
+
+// NewMetrics создает метрики и регистрирует их в reg (nil - prometheus.DefaultRegisterer).
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+
+	m := &Metrics{
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "retry_retries_total",
+			Help: "Количество повторов после неудачной попытки",
+		}, []string{"operation"}),
+		giveUps: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "retry_give_ups_total",
+			Help: "Количество вызовов Retry, завершившихся ошибкой",
+		}, []string{"operation"}),
+		delays: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "retry_delay_seconds",
+			Help:    "Задержка перед повтором",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 12), // 10ms..~20s
+		}, []string{"operation"}),
+		attempts: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "retry_give_up_attempts",
+			Help:    "Количество попыток, после которых Retry сдался",
+			Buckets: prometheus.LinearBuckets(1, 1, 10),
+		}, []string{"operation"}),
+	}
+
+	reg.MustRegister(m.retries, m.giveUps, m.delays, m.attempts)
+
+	return m
+}
+
+// Hooks возвращает хуки, которые пишут метрики для operation.
+func (m *Metrics) Hooks(operation string) Hooks {
+	// @idiomatic: resolve labels once instead of on each call
+	retries := m.retries.WithLabelValues(operation)
+	giveUps := m.giveUps.WithLabelValues(operation)
+	delays := m.delays.WithLabelValues(operation)
+	attempts := m.attempts.WithLabelValues(operation)
+
+	return Hooks{
+		OnRetry: func(_ context.Context, _ int, _ error, nextDelay time.Duration) {
+			retries.Inc()
+			delays.Observe(nextDelay.Seconds())
+		},
+		OnGiveUp: func(_ context.Context, n int, _ error) {
+			giveUps.Inc()
+			attempts.Observe(float64(n))
+		},
+	}
+}
+
+// TracingHooks возвращает хуки, которые добавляют события в текущий span из ctx (см. otel.Tracer(...).Start).
+// Если span в ctx нет, то события уходят в no-op span.
+func TracingHooks() Hooks {
+	return Hooks{
+		OnRetry: func(ctx context.Context, attempt int, err error, nextDelay time.Duration) {
+			trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+				attribute.Int("retry.attempt", attempt),
+				attribute.String("retry.error", err.Error()),
+				attribute.Int64("retry.next_delay_ms", nextDelay.Milliseconds()),
+			))
+		},
+		OnGiveUp: func(ctx context.Context, attempts int, err error) {
+			span := trace.SpanFromContext(ctx)
+			span.RecordError(err, trace.WithAttributes(attribute.Int("retry.attempts", attempts)))
+			span.SetStatus(codes.Error, "retry gave up")
+		},
+	}
+}
@@ -0,0 +1,78 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+	"time"
+)
+
+func TestObservability(t *testing.T) {
+	failing := func() (int, error) {
+		return 0, errors.New("some error")
+	}
+
+	t.Run("prometheus", func(t *testing.T) {
+		m := NewMetrics(prometheus.NewRegistry())
+		config := NewConfig(
+			WithMaxAttempts(3),
+			WithBackoff(Constant(time.Millisecond)),
+			WithHooks(m.Hooks("test")),
+		)
+
+		_, _ = Retry[int](t.Context(), failing, config)
+
+		if got := testutil.ToFloat64(m.retries.WithLabelValues("test")); got != 2 {
+			t.Errorf("got %v retries, want 2", got)
+		}
+
+		if got := testutil.ToFloat64(m.giveUps.WithLabelValues("test")); got != 1 {
+			t.Errorf("got %v give ups, want 1", got)
+		}
+	})
+
+	t.Run("tracing", func(t *testing.T) {
+		recorder := tracetest.NewSpanRecorder()
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+		ctx, span := tp.Tracer("test").Start(t.Context(), "call")
+		config := NewConfig(
+			WithMaxAttempts(2),
+			WithBackoff(Constant(time.Millisecond)),
+			WithHooks(TracingHooks()),
+		)
+
+		_, _ = Retry[int](ctx, failing, config)
+		span.End()
+
+		spans := recorder.Ended()
+		if len(spans) != 1 {
+			t.Fatalf("got %v spans, want 1", len(spans))
+		}
+
+		// 1 повтор + событие ошибки при give up
+		var names []string
+		for _, e := range spans[0].Events() {
+			names = append(names, e.Name)
+		}
+		if len(names) != 2 || names[0] != "retry" || names[1] != "exception" {
+			t.Errorf("got events %v, want [retry exception]", names)
+		}
+	})
+
+	t.Run("hooks_are_chained", func(t *testing.T) {
+		var calls int
+		count := Hooks{OnGiveUp: func(context.Context, int, error) { calls++ }}
+
+		config := NewConfig(WithMaxAttempts(1), WithHooks(count, TracingHooks(), count))
+		_, _ = Retry[int](t.Context(), failing, config)
+
+		if calls != 2 {
+			t.Errorf("got %v calls, want 2", calls)
+		}
+	})
+}
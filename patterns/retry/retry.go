@@ -2,8 +2,8 @@ package retry
 
 import (
 	"context"
-	"math"
-	"math/rand"
+	"errors"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
 	"time"
 )
 
@@ -12,59 +12,177 @@ import (
 // Требования:
 //   - принимает функцию для повторного выполнения
 //   - принимает следующие конфигурационные параметры
-//     MaxAttempts - максимальное кол-во попыток
+//     MaxAttempts - максимальное кол-во попыток (не меньше 1)
 //     Delay - задержка между вызовами
 //     BackoffFactor - коэффициент увеличения задержки при каждом повторе (0 - если не увеличивать, больше 0 если требуется)
-//     MaxDelay - максимально возможная задержка
+//     MaxDelay - максимально возможная задержка (0 - повторять без задержки)
 //     JitterFactor - коэффициент до которого может случайным образом увеличиваться delay (0 - если не увеличивать, больше 0 если требуется)
 //     RetryableChecker - функция принимающая ошибку и возвращающая true в случае если нужен повторный вызов, иначе false
+//     Backoff - стратегия задержек, если задана, то Delay, BackoffFactor и JitterFactor не используются
+//     AttemptTimeout - ограничение времени одной попытки
+//     Budget - общий бюджет повторов, при его исчерпании повторы прекращаются с ErrRetryBudgetExhausted
 //   - реагирует на отмену через контекст
 //   - функция вызывается хотя бы 1 раз независимо от RetryableChecker
+//   - ошибка, обернутая в Permanent, прекращает повторы
+//   - если ошибка реализует DelayHinter, то задержка берется из нее (но не больше MaxDelay)
+//   - при неудаче возвращает *RetryError с ошибками всех попыток
+//
+// fn не получает контекст, поэтому прервать ее по AttemptTimeout нельзя: попытка считается неудачной
+// с context.DeadlineExceeded, а fn дорабатывает в фоне и ее результат отбрасывается.
+// Если fn умеет останавливаться по контексту, то лучше RetryContext.
 func Retry[T any](ctx context.Context, fn RetryableFunc[T], config *Config) (T, error) {
 	if config == nil {
 		config = DefaultConfig()
 	}
 
+	if config.AttemptTimeout <= 0 {
+		return RetryContext(ctx, func(context.Context) (T, error) {
+			return fn()
+		}, config)
+	}
+
+	return RetryContext(ctx, func(attemptCtx context.Context) (T, error) {
+		type result struct {
+			val T
+			err error
+		}
+
+		// буфер 1, чтобы брошенная по таймауту fn не зависла на записи
+		resCh := make(chan result, 1)
+		go func() {
+			val, err := fn()
+			resCh <- result{val, err}
+		}()
+
+		select {
+		case res := <-resCh:
+			return res.val, res.err
+		case <-attemptCtx.Done():
+			var zero T
+			return zero, attemptCtx.Err()
+		}
+	}, config)
+}
+
+// RetryContext - как Retry, но fn получает контекст попытки, номер попытки можно узнать через AttemptFromContext.
+// Если задан AttemptTimeout, то контекст попытки отменяется по его истечении, а попытка считается неудачной.
+func RetryContext[T any](ctx context.Context, fn RetryableContextFunc[T], config *Config) (T, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	checkMaxAttempts(config.MaxAttempts)
+
 	var zero T
-	var lastErr error
+	var errs []error
+	var delay time.Duration
+
+	clk := clock.OrReal(config.Clock)
+
+	backoff := config.Backoff
+	if backoff == nil {
+		backoff = configBackoff(config)
+	}
 
-	for attempt := range config.MaxAttempts {
+	// attempts - количество выполненных попыток, ошибка контекста (если есть) попыткой не считается.
+	fail := func(attempts int, err error) (T, error) {
+		errs = append(errs, err)
+		retryErr := &RetryError{Attempts: attempts, Err: errors.Join(errs...)}
+		if config.OnGiveUp != nil {
+			config.OnGiveUp(ctx, attempts, retryErr)
+		}
+		return zero, retryErr
+	}
+
+	// выход - по успеху, последней попытке или отмене ctx
+	for attempt := 0; ; attempt++ {
 		if ctx.Err() != nil {
-			return zero, ctx.Err()
+			// в том числе до первой попытки: OnGiveUp вызывается, ошибка - *RetryError с Attempts == 0
+			return fail(attempt, ctx.Err())
 		}
 
-		res, err := fn()
+		res, err := runAttempt(ctx, fn, attempt+1, config.AttemptTimeout)
 		if err == nil {
+			if config.Budget != nil {
+				config.Budget.Deposit()
+			}
 			return res, nil
 		}
 
-		lastErr = err
+		if IsPermanent(err) || !config.RetryableChecker(err) || attempt == config.MaxAttempts-1 {
+			return fail(attempt+1, err)
+		}
+
+		if config.Budget != nil && !config.Budget.TryWithdraw() {
+			errs = append(errs, err)
+			return fail(attempt+1, ErrRetryBudgetExhausted)
+		}
+
+		errs = append(errs, err)
 
-		if !config.RetryableChecker(err) {
-			return zero, err
+		delay = backoff.Delay(attempt, delay)
+		if hint := delayHint(err); hint > 0 {
+			delay = hint
 		}
+		// MaxDelay ограничивает любую задержку, в том числе из подсказки: 0 - повторять без задержки
+		delay = min(delay, config.MaxDelay)
 
-		// @idiomatic: convert time.Duration to int64/float64 returns nanoseconds
-		d := float64(config.Delay) * math.Pow(config.BackoffFactor, float64(attempt))
-		d += d * rand.Float64() * config.JitterFactor
+		if config.OnRetry != nil {
+			config.OnRetry(ctx, attempt+1, err, delay)
+		}
 
-		// @idiomatic: type casting to time.Duration accepts nanoseconds
-		delay := min(time.Duration(d), config.MaxDelay)
+		timer := clk.NewTimer(delay)
 
 		select {
 		case <-ctx.Done():
-			return zero, ctx.Err()
-		case <-time.After(delay):
+			timer.Stop()
+			return fail(attempt+1, ctx.Err())
+		case <-timer.C():
 			// waiting
 		}
 	}
+}
+
+func runAttempt[T any](ctx context.Context, fn RetryableContextFunc[T], attempt int, timeout time.Duration) (T, error) {
+	ctx = context.WithValue(ctx, attemptKey{}, attempt)
 
-	return zero, lastErr
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return fn(attemptCtx)
 }
 
 // RetryableFunc запускаемая функция.
 type RetryableFunc[T any] func() (T, error)
 
+// RetryableContextFunc запускаемая функция, получающая контекст попытки.
+type RetryableContextFunc[T any] func(ctx context.Context) (T, error)
+
+// attemptKey - неэкспортируемый тип ключа, чтобы не пересечься с ключами других пакетов.
+type attemptKey struct{}
+
+// AttemptFromContext возвращает номер текущей попытки (начиная с 1) или 0, если ctx получен не из RetryContext.
+func AttemptFromContext(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptKey{}).(int)
+	return attempt
+}
+
+// OnRetryFunc вызывается после неудачной попытки attempt (начиная с 1) перед ожиданием nextDelay.
+type OnRetryFunc func(ctx context.Context, attempt int, err error, nextDelay time.Duration)
+
+// OnGiveUpFunc вызывается, когда Retry сдается: попытки кончились, ошибка не повторяемая или отменен ctx.
+// err - итоговый *RetryError.
+type OnGiveUpFunc func(ctx context.Context, attempts int, err error)
+
+// Hooks - набор хуков, удобен для готовых интеграций (метрики, трассировка).
+type Hooks struct {
+	OnRetry  OnRetryFunc
+	OnGiveUp OnGiveUpFunc
+}
+
 // RetryableCheckerFunc функция которая должна вернуть true в случае если необходимо повторить вызов
 type RetryableCheckerFunc func(error) bool
 
@@ -75,6 +193,12 @@ type Config struct {
 	BackoffFactor    float64
 	JitterFactor     float64
 	RetryableChecker RetryableCheckerFunc
+	Clock            clock.Clock   // nil - clock.Real
+	Backoff          Backoff       // nil - Delay * BackoffFactor^attempt + jitter до JitterFactor
+	AttemptTimeout   time.Duration // 0 - без ограничения
+	OnRetry          OnRetryFunc
+	OnGiveUp         OnGiveUpFunc
+	Budget           *RetryBudget // nil - без ограничения, один бюджет обычно делят все вызовы к одной зависимости
 }
 
 // DefaultConfig возвращает конфигурацию по умолчанию.
@@ -89,6 +213,7 @@ func DefaultConfig() *Config {
 		RetryableChecker: func(err error) bool {
 			return true
 		},
+		Clock: clock.Real,
 	}
 }
 
@@ -103,6 +228,8 @@ func NewConfig(opts ...ConfigOptionFunc) *Config {
 type ConfigOptionFunc func(*Config)
 
 func WithMaxAttempts(val int) ConfigOptionFunc {
+	checkMaxAttempts(val)
+
 	return func(c *Config) {
 		c.MaxAttempts = val
 	}
@@ -146,3 +273,85 @@ func WithRetryableChecker(val RetryableCheckerFunc) ConfigOptionFunc {
 		c.RetryableChecker = val
 	}
 }
+
+func WithClock(val clock.Clock) ConfigOptionFunc {
+	return func(c *Config) {
+		c.Clock = val
+	}
+}
+
+func WithBackoff(val Backoff) ConfigOptionFunc {
+	return func(c *Config) {
+		c.Backoff = val
+	}
+}
+
+func WithAttemptTimeout(val time.Duration) ConfigOptionFunc {
+	if val < 0 {
+		panic("attempt timeout must be greater or equal than 0, pass 0 if you want to disable timeout")
+	}
+
+	return func(c *Config) {
+		c.AttemptTimeout = val
+	}
+}
+
+func WithRetryBudget(val *RetryBudget) ConfigOptionFunc {
+	return func(c *Config) {
+		c.Budget = val
+	}
+}
+
+func WithOnRetry(val OnRetryFunc) ConfigOptionFunc {
+	return func(c *Config) {
+		c.OnRetry = val
+	}
+}
+
+func WithOnGiveUp(val OnGiveUpFunc) ConfigOptionFunc {
+	return func(c *Config) {
+		c.OnGiveUp = val
+	}
+}
+
+// WithHooks добавляет хуки к уже заданным, например, чтобы одновременно писать метрики и трассировку.
+func WithHooks(hooks ...Hooks) ConfigOptionFunc {
+	return func(c *Config) {
+		for _, h := range hooks {
+			c.OnRetry = chainOnRetry(c.OnRetry, h.OnRetry)
+			c.OnGiveUp = chainOnGiveUp(c.OnGiveUp, h.OnGiveUp)
+		}
+	}
+}
+
+func chainOnRetry(a, b OnRetryFunc) OnRetryFunc {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	return func(ctx context.Context, attempt int, err error, nextDelay time.Duration) {
+		a(ctx, attempt, err, nextDelay)
+		b(ctx, attempt, err, nextDelay)
+	}
+}
+
+func chainOnGiveUp(a, b OnGiveUpFunc) OnGiveUpFunc {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	return func(ctx context.Context, attempts int, err error) {
+		a(ctx, attempts, err)
+		b(ctx, attempts, err)
+	}
+}
+
+func checkMaxAttempts(val int) {
+	if val < 1 {
+		panic("max attempts must be greater than 0")
+	}
+}
@@ -1,7 +1,11 @@
 package retry
 
 import (
+	"context"
 	"errors"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
+	"slices"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -157,4 +161,278 @@ func TestRetry(t *testing.T) {
 			t.Fatalf("elapsed %v, want <= %v", elapsed, maxElapsed)
 		}
 	})
+
+	t.Run("fake_clock_backoff", func(t *testing.T) {
+		c := clock.NewFake()
+
+		var retries atomic.Int32
+		config := NewConfig(
+			WithMaxAttempts(3),
+			WithDelay(time.Second),
+			WithMaxDelay(time.Minute),
+			WithBackoffFactor(2),
+			WithJitterFactor(0),
+			WithClock(c),
+		)
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := Retry[int](t.Context(), func() (int, error) {
+				retries.Add(1)
+				return 0, errors.New("some error")
+			}, config)
+			done <- err
+		}()
+
+		// 1s, затем 2s
+		for attempt, delay := range []time.Duration{time.Second, 2 * time.Second} {
+			c.BlockUntil(1)
+
+			c.Advance(delay - time.Millisecond)
+			if got := retries.Load(); got != int32(attempt+1) {
+				t.Fatalf("got %v retries, want %v", got, attempt+1)
+			}
+
+			c.Advance(time.Millisecond)
+			for retries.Load() != int32(attempt+2) {
+				time.Sleep(time.Millisecond)
+			}
+		}
+
+		// после последней попытки не ждет
+		select {
+		case err := <-done:
+			if err == nil {
+				t.Fatalf("want error")
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("retry did not finish")
+		}
+	})
+
+	t.Run("errors_are_joined", func(t *testing.T) {
+		errA := errors.New("a")
+		errB := errors.New("b")
+
+		var retries int
+		config := NewConfig(WithMaxAttempts(2), WithBackoff(Constant(0)))
+
+		_, err := Retry[int](t.Context(), func() (int, error) {
+			retries++
+			if retries == 1 {
+				return 0, errA
+			}
+			return 0, errB
+		}, config)
+
+		var retryErr *RetryError
+		if !errors.As(err, &retryErr) {
+			t.Fatalf("got %T, want *RetryError", err)
+		}
+
+		if retryErr.Attempts != 2 {
+			t.Errorf("got %v attempts, want 2", retryErr.Attempts)
+		}
+
+		if !errors.Is(err, errA) || !errors.Is(err, errB) {
+			t.Errorf("got %v, want both errors", err)
+		}
+	})
+
+	t.Run("permanent_should_not_retry", func(t *testing.T) {
+		errA := errors.New("a")
+
+		var retries int
+		config := NewConfig(WithMaxAttempts(5), WithBackoff(Constant(0)))
+
+		_, err := Retry[int](t.Context(), func() (int, error) {
+			retries++
+			return 0, Permanent(errA)
+		}, config)
+
+		if retries != 1 {
+			t.Fatalf("got %v retries, want 1", retries)
+		}
+
+		if !errors.Is(err, errA) || !IsPermanent(err) {
+			t.Errorf("got %v, want permanent %v", err, errA)
+		}
+	})
+
+	t.Run("attempt_timeout", func(t *testing.T) {
+		var retries int
+		config := NewConfig(
+			WithMaxAttempts(3),
+			WithBackoff(Constant(0)),
+			WithAttemptTimeout(10*time.Millisecond),
+		)
+
+		val, err := RetryContext[int](t.Context(), func(ctx context.Context) (int, error) {
+			retries++
+			if retries < 3 {
+				// зависшая попытка
+				<-ctx.Done()
+				return 0, ctx.Err()
+			}
+			return 1, nil
+		}, config)
+
+		if err != nil {
+			t.Fatalf("got error %v", err)
+		}
+
+		if val != 1 || retries != 3 {
+			t.Fatalf("got val=%v retries=%v, want val=1 retries=3", val, retries)
+		}
+	})
+
+	t.Run("attempt_timeout_without_context", func(t *testing.T) {
+		var retries atomic.Int32
+		release := make(chan struct{})
+		defer close(release)
+
+		config := NewConfig(
+			WithMaxAttempts(3),
+			WithBackoff(Constant(0)),
+			WithAttemptTimeout(10*time.Millisecond),
+		)
+
+		val, err := Retry[int](t.Context(), func() (int, error) {
+			if retries.Add(1) < 3 {
+				// зависшая попытка, контекста у нее нет
+				<-release
+				return 0, errors.New("too late")
+			}
+			return 1, nil
+		}, config)
+
+		if err != nil {
+			t.Fatalf("got error %v", err)
+		}
+
+		if val != 1 || retries.Load() != 3 {
+			t.Fatalf("got val=%v retries=%v, want val=1 retries=3", val, retries.Load())
+		}
+	})
+
+	t.Run("zero_max_delay_should_not_wait", func(t *testing.T) {
+		var retries int
+
+		config := NewConfig(WithMaxAttempts(3), WithBackoff(Constant(time.Hour)), WithMaxDelay(0))
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := Retry[int](t.Context(), func() (int, error) {
+				retries++
+				return 0, errors.New("some error")
+			}, config)
+			done <- err
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("got waiting with MaxDelay == 0")
+		}
+
+		if retries != 3 {
+			t.Fatalf("got %v retries, want 3", retries)
+		}
+	})
+
+	t.Run("canceled_before_first_attempt", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+
+		var calls, gaveUp int
+		config := NewConfig(WithOnGiveUp(func(_ context.Context, attempts int, _ error) {
+			gaveUp++
+			if attempts != 0 {
+				t.Errorf("got %v attempts in OnGiveUp, want 0", attempts)
+			}
+		}))
+
+		_, err := Retry[int](ctx, func() (int, error) {
+			calls++
+			return 1, nil
+		}, config)
+
+		var retryErr *RetryError
+		if !errors.As(err, &retryErr) || retryErr.Attempts != 0 || !errors.Is(err, context.Canceled) {
+			t.Fatalf("got %v, want *RetryError with 0 attempts and context.Canceled", err)
+		}
+		if calls != 0 || gaveUp != 1 {
+			t.Errorf("got calls=%v gave up=%v, want 0 and 1", calls, gaveUp)
+		}
+	})
+
+	t.Run("zero_max_attempts_panics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("got no panic")
+			}
+		}()
+
+		_, _ = Retry[int](t.Context(), func() (int, error) {
+			return 1, nil
+		}, &Config{})
+	})
+
+	t.Run("canceled_during_wait", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		errA := errors.New("a")
+
+		config := NewConfig(WithMaxAttempts(3), WithBackoff(Constant(time.Hour)), WithMaxDelay(time.Hour))
+
+		_, err := Retry[int](ctx, func() (int, error) {
+			cancel()
+			return 0, errA
+		}, config)
+
+		var retryErr *RetryError
+		if !errors.As(err, &retryErr) || retryErr.Attempts != 1 {
+			t.Fatalf("got %v, want *RetryError with 1 attempt", err)
+		}
+
+		if !errors.Is(err, context.Canceled) || !errors.Is(err, errA) {
+			t.Errorf("got %v, want canceled and %v", err, errA)
+		}
+	})
+	t.Run("hooks_and_attempt", func(t *testing.T) {
+		var seen []int
+		var retried []int
+		var gaveUp int
+
+		config := NewConfig(
+			WithMaxAttempts(3),
+			WithBackoff(Constant(time.Millisecond)),
+			WithOnRetry(func(_ context.Context, attempt int, err error, nextDelay time.Duration) {
+				retried = append(retried, attempt)
+				if nextDelay != time.Millisecond {
+					t.Errorf("got delay %v, want 1ms", nextDelay)
+				}
+			}),
+			WithOnGiveUp(func(_ context.Context, attempts int, err error) {
+				gaveUp = attempts
+			}),
+		)
+
+		_, _ = RetryContext[int](t.Context(), func(ctx context.Context) (int, error) {
+			seen = append(seen, AttemptFromContext(ctx))
+			return 0, errors.New("some error")
+		}, config)
+
+		if !slices.Equal(seen, []int{1, 2, 3}) {
+			t.Errorf("got attempts %v, want [1 2 3]", seen)
+		}
+
+		// после последней попытки OnRetry не вызывается
+		if !slices.Equal(retried, []int{1, 2}) {
+			t.Errorf("got retried %v, want [1 2]", retried)
+		}
+
+		if gaveUp != 3 {
+			t.Errorf("got give up after %v attempts, want 3", gaveUp)
+		}
+	})
 }
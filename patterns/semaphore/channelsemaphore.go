@@ -1,8 +1,20 @@
 package semaphore
 
+import (
+	"context"
+)
+
 // channelSemaphore — семафор реализованный через каналы.
+//
+// Простой вариант - буферизированный канал на limit слотов (занять = записать, освободить = прочитать) -
+// не умеет ни n слотов за раз без обгонов, ни менять limit (емкость канала фиксирована).
+// Поэтому канал здесь хранит само состояние: канал с буфером 1, в котором лежит fifo. Кто прочитал состояние,
+// тот владеет им, пока не запишет обратно. В отличие от sync.Mutex, ожидание такой "блокировки" можно прервать
+// через select с ctx.Done().
+//
+// @idiomatic: channel as a lock - буферизированный канал на 1 элемент как отменяемый mutex
 type channelSemaphore struct {
-	ch chan struct{}
+	state chan *fifo
 }
 
 // NewChannelSemaphore public constructor of channelSemaphore.
@@ -10,24 +22,74 @@ type channelSemaphore struct {
 // @idiomatic: protect chain size of private implementation
 // @idiomatic: return pointer to private implementation as Interface
 func NewChannelSemaphore(limit int) Semaphore {
-	return &channelSemaphore{
-		ch: make(chan struct{}, limit),
+	checkLimit(limit)
+
+	s := &channelSemaphore{
+		state: make(chan *fifo, 1),
 	}
+	s.state <- &fifo{limit: limit}
+	return s
 }
 
-func (s *channelSemaphore) Acquire() {
-	s.ch <- struct{}{}
-}
+func (s *channelSemaphore) Acquire(ctx context.Context, n int) error {
+	checkWeight(n)
 
-func (s *channelSemaphore) Release() {
-	<-s.ch
-}
+	var state *fifo
+	select {
+	case state = <-s.state:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if state.tryAcquire(n) {
+		s.state <- state
+		return nil
+	}
+	elem := state.enqueue(n)
+	s.state <- state
 
-func (s *channelSemaphore) TryAcquire() bool {
 	select {
-	case s.ch <- struct{}{}:
-		return true
-	default:
-		return false
+	case <-elem.Value.(*waiter).ready:
+		return nil
+	case <-ctx.Done():
+		// состояние нужно обязательно, поэтому здесь ждем без ctx
+		state := <-s.state
+		if !state.cancel(elem) {
+			state.release(n)
+		}
+		s.state <- state
+		return ctx.Err()
 	}
 }
+
+func (s *channelSemaphore) Release(n int) {
+	checkWeight(n)
+
+	state := <-s.state
+	defer func() {
+		s.state <- state
+	}()
+
+	state.release(n)
+}
+
+func (s *channelSemaphore) TryAcquire(n int) bool {
+	checkWeight(n)
+
+	// состояние держат недолго (никто не ждет слотов, держа его), поэтому его ждем
+	state := <-s.state
+	defer func() {
+		s.state <- state
+	}()
+
+	return state.tryAcquire(n)
+}
+
+func (s *channelSemaphore) Resize(limit int) {
+	state := <-s.state
+	defer func() {
+		s.state <- state
+	}()
+
+	state.resize(limit)
+}
@@ -0,0 +1,88 @@
+package semaphore
+
+import (
+	"container/list"
+)
+
+// fifo - состояние взвешенного семафора с очередью ожидающих. Не потокобезопасно: mutexSemaphore и
+// channelSemaphore защищают его каждый своим способом.
+//
+// Алгоритм как в golang.org/x/sync/semaphore:
+//   - запрос берет слоты сразу, только если они есть и очередь пуста
+//   - иначе встает в конец очереди, а release будит ожидающих с начала очереди, пока первому хватает места
+type fifo struct {
+	limit   int
+	cur     int
+	waiters list.List // *waiter
+}
+
+type waiter struct {
+	n     int
+	ready chan struct{} // закрывается, когда слоты выданы
+}
+
+func (f *fifo) tryAcquire(n int) bool {
+	if f.waiters.Len() == 0 && f.cur+n <= f.limit {
+		f.cur += n
+		return true
+	}
+	return false
+}
+
+func (f *fifo) enqueue(n int) *list.Element {
+	return f.waiters.PushBack(&waiter{n: n, ready: make(chan struct{})})
+}
+
+// cancel убирает ожидающего после отмены ctx. Возвращает false, если слоты уже выданы (отмена опоздала).
+func (f *fifo) cancel(elem *list.Element) bool {
+	w := elem.Value.(*waiter)
+
+	select {
+	case <-w.ready:
+		return false
+	default:
+	}
+
+	isFront := f.waiters.Front() == elem
+	f.waiters.Remove(elem)
+
+	// если ушел первый, то следующие за ним могут поместиться
+	if isFront {
+		f.notify()
+	}
+	return true
+}
+
+func (f *fifo) release(n int) {
+	if f.cur-n < 0 {
+		panic("semaphore: released more than held")
+	}
+	f.cur -= n
+	f.notify()
+}
+
+func (f *fifo) resize(limit int) {
+	checkLimit(limit)
+	f.limit = limit
+	f.notify()
+}
+
+// notify выдает слоты ожидающим с начала очереди.
+func (f *fifo) notify() {
+	for {
+		front := f.waiters.Front()
+		if front == nil {
+			return
+		}
+
+		w := front.Value.(*waiter)
+		if f.cur+w.n > f.limit {
+			// не обгоняем первого, даже если следующим места хватит
+			return
+		}
+
+		f.cur += w.n
+		f.waiters.Remove(front)
+		close(w.ready)
+	}
+}
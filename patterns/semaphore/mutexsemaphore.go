@@ -1,49 +1,73 @@
 package semaphore
 
 import (
+	"context"
 	"sync"
 )
 
 // mutexSemaphore — семафор, реализованный на mutex и подсчете количества.
+// Ожидание - на канале waiter.ready, а не на mutex: иначе ожидание нельзя было бы отменить через ctx.
 type mutexSemaphore struct {
-	cap int
-	mu  sync.Mutex
+	mu    sync.Mutex
+	state fifo
 }
 
 // NewMutexSemaphore public constructor of mutexSemaphore.
 func NewMutexSemaphore(limit int) Semaphore {
+	checkLimit(limit)
+
 	return &mutexSemaphore{
-		cap: limit,
+		state: fifo{limit: limit},
 	}
 }
 
-func (s *mutexSemaphore) Acquire() {
+func (s *mutexSemaphore) Acquire(ctx context.Context, n int) error {
+	checkWeight(n)
+
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	if s.state.tryAcquire(n) {
+		s.mu.Unlock()
+		return nil
+	}
+	elem := s.state.enqueue(n)
+	s.mu.Unlock()
 
-	if s.cap > 0 {
-		s.cap--
+	select {
+	case <-elem.Value.(*waiter).ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if !s.state.cancel(elem) {
+			// слоты выдали одновременно с отменой - возвращаем их
+			s.state.release(n)
+		}
+		return ctx.Err()
 	}
 }
 
-func (s *mutexSemaphore) Release() {
+func (s *mutexSemaphore) Release(n int) {
+	checkWeight(n)
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.cap++
+	s.state.release(n)
 }
 
-func (s *mutexSemaphore) TryAcquire() bool {
-	if !s.mu.TryLock() {
-		return false
-	}
+func (s *mutexSemaphore) TryAcquire(n int) bool {
+	checkWeight(n)
+
+	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// лимитов не достигли
-	if s.cap > 0 {
-		s.cap--
-		return true
-	}
+	return s.state.tryAcquire(n)
+}
+
+func (s *mutexSemaphore) Resize(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	return false
+	s.state.resize(limit)
 }
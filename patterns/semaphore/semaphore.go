@@ -1,9 +1,31 @@
 package semaphore
 
+import (
+	"context"
+)
+
 // Semaphore — это механизм синхронизации, который ограничивает количество одновременно выполняющихся goroutines,
 // например, при обращении к ресурсу (файлу, API, БД и т.д.).
+//
+// Семафор взвешенный: запрос может занять сразу n слотов (например, по размеру задачи в памяти).
+// Ожидающие обслуживаются строго по очереди (FIFO): если первым в очереди стоит большой запрос, то маленькие
+// ждут за ним, даже если для них место уже есть. Иначе поток маленьких запросов никогда не оставит места
+// большому (starvation).
 type Semaphore interface {
-	Acquire()         // Захватить слот (ожидание, если нет свободных)
-	Release()         // Освободить слот
-	TryAcquire() bool // Попробовать захватить без ожидания
+	Acquire(ctx context.Context, n int) error // Захватить n слотов (ожидание, если нет свободных), ошибка - ctx отменен
+	Release(n int)                            // Освободить n слотов
+	TryAcquire(n int) bool                    // Попробовать захватить без ожидания (и без обгона очереди)
+	Resize(limit int)                         // Изменить лимит, уже захваченные слоты не отбираются
+}
+
+func checkWeight(n int) {
+	if n <= 0 {
+		panic("semaphore: n must be greater than 0")
+	}
+}
+
+func checkLimit(limit int) {
+	if limit < 0 {
+		panic("semaphore: limit must not be negative")
+	}
 }
@@ -1,140 +1,272 @@
 package semaphore
 
 import (
+	"context"
+	"errors"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
 func TestChannelSemaphore(t *testing.T) {
-	t.Run("sequentially", func(t *testing.T) {
-		s := NewChannelSemaphore(1)
-		sequentially(t, s)
-	})
-
-	t.Run("sized", func(t *testing.T) {
-		s := NewChannelSemaphore(3)
-		sized(t, s)
-	})
+	conformance(t, NewChannelSemaphore)
+}
 
-	t.Run("concurrently", func(t *testing.T) {
-		s := NewChannelSemaphore(3)
-		concurrently(t, s)
-	})
+func TestMutexSemaphore(t *testing.T) {
+	conformance(t, NewMutexSemaphore)
+}
 
-	t.Run("try_acquire", func(t *testing.T) {
-		s := NewChannelSemaphore(1)
-		tryAcquire(t, s)
-	})
+func TestSpinSemaphore(t *testing.T) {
+	conformance(t, NewSpinSemaphore)
 }
 
-func TestMutexSemaphore(t *testing.T) {
+// conformance - общие требования ко всем реализациям Semaphore.
+func conformance(t *testing.T, newSemaphore func(limit int) Semaphore) {
 	t.Run("sequentially", func(t *testing.T) {
-		s := NewMutexSemaphore(1)
-		sequentially(t, s)
+		s := newSemaphore(1)
+		for i := 0; i < 10; i++ {
+			mustAcquire(t, s, 1)
+			s.Release(1)
+		}
 	})
 
 	t.Run("sized", func(t *testing.T) {
-		s := NewMutexSemaphore(3)
-		sized(t, s)
+		s := newSemaphore(3)
+		mustAcquire(t, s, 1)
+		mustAcquire(t, s, 1)
+		mustAcquire(t, s, 1)
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			s.Release(1)
+		}()
+
+		mustAcquire(t, s, 1)
 	})
 
-	t.Run("concurrently", func(t *testing.T) {
-		s := NewMutexSemaphore(3)
-		concurrently(t, s)
+	t.Run("concurrently_never_exceeds_limit", func(t *testing.T) {
+		s := newSemaphore(3)
+
+		var cur, peak atomic.Int64
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				n := 1 + i%2
+				mustAcquire(t, s, n)
+				now := cur.Add(int64(n))
+				for p := peak.Load(); now > p && !peak.CompareAndSwap(p, now); p = peak.Load() {
+				}
+				time.Sleep(5 * time.Millisecond)
+				cur.Add(-int64(n))
+				s.Release(n)
+			}()
+		}
+		wg.Wait()
+
+		if peak.Load() > 3 {
+			t.Errorf("got %v slots in use, want at most 3", peak.Load())
+		}
 	})
 
 	t.Run("try_acquire", func(t *testing.T) {
-		s := NewMutexSemaphore(1)
-		tryAcquire(t, s)
-	})
-}
+		s := newSemaphore(2)
+		mustAcquire(t, s, 1)
 
-func TestSpinSemaphore(t *testing.T) {
-	t.Run("sequentially", func(t *testing.T) {
-		s := NewSpinSemaphore(1)
-		sequentially(t, s)
-	})
+		if s.TryAcquire(2) {
+			t.Fatal("got acquired 2 of 1 free")
+		}
+		if !s.TryAcquire(1) {
+			t.Fatal("got not acquired 1 of 1 free")
+		}
 
-	t.Run("sized", func(t *testing.T) {
-		s := NewSpinSemaphore(3)
-		sized(t, s)
+		s.Release(2)
+		if !s.TryAcquire(2) {
+			t.Fatal("got not acquired 2 of 2 free")
+		}
 	})
 
-	t.Run("concurrently", func(t *testing.T) {
-		s := NewSpinSemaphore(3)
-		concurrently(t, s)
+	t.Run("context_cancel", func(t *testing.T) {
+		s := newSemaphore(1)
+		mustAcquire(t, s, 1)
+
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+		defer cancel()
+
+		if err := s.Acquire(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got %v, want context.DeadlineExceeded", err)
+		}
+
+		// отмененный запрос не занимает слоты и не держит очередь
+		s.Release(1)
+		mustAcquire(t, s, 1)
 	})
 
-	t.Run("try_acquire", func(t *testing.T) {
-		s := NewSpinSemaphore(1)
-		tryAcquire(t, s)
+	t.Run("fifo_large_request_not_starved", func(t *testing.T) {
+		s := newSemaphore(3)
+		mustAcquire(t, s, 2)
+
+		large := acquireAsync(s, 3)
+		time.Sleep(10 * time.Millisecond)
+
+		// 1 слот свободен, но большой запрос стоит раньше
+		if s.TryAcquire(1) {
+			t.Fatal("got small request ahead of large one")
+		}
+		small := acquireAsync(s, 1)
+
+		s.Release(2)
+		waitAcquired(t, large, "large")
+
+		select {
+		case <-small:
+			t.Fatal("got small request while large one holds all slots")
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		s.Release(3)
+		waitAcquired(t, small, "small")
 	})
-}
 
-func sequentially(t *testing.T, s Semaphore) {
-	for i := 0; i < 10; i++ {
-		s.Acquire()
-		s.Release()
-	}
+	t.Run("cancel_first_waiter_unblocks_next", func(t *testing.T) {
+		s := newSemaphore(2)
+		mustAcquire(t, s, 1)
 
-	t.Log("expected")
-}
+		ctx, cancel := context.WithCancel(t.Context())
+		large := make(chan error, 1)
+		go func() {
+			large <- s.Acquire(ctx, 2)
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		small := acquireAsync(s, 1)
+		cancel()
 
-func sized(t *testing.T, s Semaphore) {
-	s.Acquire()
-	s.Acquire()
-	s.Acquire()
+		if err := <-large; !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v, want context.Canceled", err)
+		}
+		waitAcquired(t, small, "small")
+	})
 
-	s.Release()
-	s.Release()
-	s.Release()
+	t.Run("cancel_behind_stuck_head", func(t *testing.T) {
+		s := newSemaphore(1)
 
-	t.Log("expected")
+		// первому не хватит места, пока не увеличат лимит
+		head := acquireAsync(s, 2)
+		time.Sleep(10 * time.Millisecond)
 
-	go func() {
-		s.Release()
-	}()
+		before := runtime.NumGoroutine()
+		for i := 0; i < 10; i++ {
+			ctx, cancel := context.WithTimeout(t.Context(), time.Millisecond)
+			if err := s.Acquire(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("got %v, want context.DeadlineExceeded", err)
+			}
+			cancel()
+		}
 
-	s.Acquire()
+		// отмененные не оставляют после себя goroutines
+		if after := runtime.NumGoroutine(); after > before+2 {
+			t.Errorf("got %v goroutines after cancels, want about %v", after, before)
+		}
 
-	t.Log("expected")
-}
+		s.Resize(3)
+		waitAcquired(t, head, "head")
+		waitAcquired(t, acquireAsync(s, 1), "after cancelled")
+	})
 
-func concurrently(t *testing.T, s Semaphore) {
-	var wg sync.WaitGroup
+	t.Run("resize", func(t *testing.T) {
+		s := newSemaphore(1)
+		mustAcquire(t, s, 1)
 
-	for i := 0; i < 10; i++ {
-		wg.Add(1)
+		waiting := acquireAsync(s, 2)
+		s.Resize(3)
+		waitAcquired(t, waiting, "after resize up")
 
-		go func() {
-			defer wg.Done()
+		// уменьшение не отбирает занятые слоты, но новые не выдаются, пока занято больше лимита
+		s.Resize(1)
+		if s.TryAcquire(1) {
+			t.Fatal("got acquired over new limit")
+		}
+		s.Release(3)
+		if !s.TryAcquire(1) {
+			t.Fatal("got not acquired after release")
+		}
+	})
 
-			s.Acquire()
-			time.Sleep(50 * time.Millisecond)
-			s.Release()
+	t.Run("negative_limit_panics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("got no panic")
+			}
 		}()
-	}
+		newSemaphore(-1)
+	})
 
-	wg.Wait()
+	t.Run("release_more_than_held_panics", func(t *testing.T) {
+		s := newSemaphore(1)
+
+		defer func() {
+			if recover() == nil {
+				t.Error("got no panic")
+			}
+		}()
+		s.Release(1)
+	})
 
-	t.Log("expected")
+	t.Run("recovered_over_release_keeps_state", func(t *testing.T) {
+		s := newSemaphore(2)
+		mustAcquire(t, s, 1)
+
+		func() {
+			defer func() {
+				_ = recover()
+			}()
+			s.Release(2)
+		}()
+
+		// занят по-прежнему 1 слот из 2
+		if !s.TryAcquire(1) {
+			t.Fatal("got not acquired 1 of 1 free")
+		}
+		if s.TryAcquire(1) {
+			t.Fatal("got acquired over limit after recovered panic")
+		}
+	})
 }
 
-func tryAcquire(t *testing.T, s Semaphore) {
-	s.Acquire()
+func mustAcquire(t *testing.T, s Semaphore, n int) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
 
-	if s.TryAcquire() {
-		t.Fatalf("expected cannot acquire")
+	if err := s.Acquire(ctx, n); err != nil {
+		t.Errorf("acquire %v: %v", n, err)
 	}
+}
 
-	s.Release()
+func acquireAsync(s Semaphore, n int) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		if s.Acquire(context.Background(), n) == nil {
+			close(done)
+		}
+	}()
+	return done
+}
 
-	if !s.TryAcquire() {
-		t.Fatalf("expected can acquire")
-	}
+func waitAcquired(t *testing.T, done <-chan struct{}, name string) {
+	t.Helper()
 
-	t.Log("expected")
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("%s request is not acquired", name)
+	}
 }
 
 func BenchmarkSpinSemaphore(b *testing.B) {
@@ -150,6 +282,8 @@ func BenchmarkChannelSemaphore(b *testing.B) {
 }
 
 func benchmarkSemaphore(b *testing.B, sem Semaphore, workers int) {
+	ctx := context.Background()
+
 	for n := 0; n < b.N; n++ {
 		var wg sync.WaitGroup
 		wg.Add(workers)
@@ -158,8 +292,8 @@ func benchmarkSemaphore(b *testing.B, sem Semaphore, workers int) {
 			go func() {
 				defer wg.Done()
 
-				sem.Acquire()
-				sem.Release()
+				_ = sem.Acquire(ctx, 1)
+				sem.Release(1)
 			}()
 		}
 		wg.Wait()
@@ -1,68 +1,173 @@
 package semaphore
 
 import (
+	"context"
 	"runtime"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // spinSemaphore — семафор реализованный на atomic подсчете количества.
+//
+// FIFO без очереди в памяти - ticket lock: каждый Acquire берет номер билета (next), и ждет, пока serving
+// не дойдет до его номера. Пока первый в очереди ждет места, остальные ждут своей очереди за ним.
+//
+// Ожидание без блокировок - это цикл проверок. Чтобы при долгом ожидании не занимать CPU, между проверками
+// сначала только уступаем процессор (runtime.Gosched), а потом спим, увеличивая паузу до spinMaxSleep.
+// Цена - задержка до spinMaxSleep между освобождением слота и его захватом.
+//
+// Отмененный билет нельзя просто выбросить - очередь на нем остановится. Поэтому он записывается в cancelled,
+// а тот, кто передает очередь дальше, пропускает такие билеты. Ожидать за отмененного никто не остается:
+// если первый в очереди ждет вечно (n > limit), отмены за ним не стоят ничего.
 type spinSemaphore struct {
-	cap atomic.Int64
+	limit atomic.Int64
+	used  atomic.Int64
+
+	next    atomic.Uint64 // следующий свободный билет
+	serving atomic.Uint64 // билет, чья сейчас очередь, меняется только под mu
+
+	// mu - только для передачи очереди и отмены, ожидание идет без него
+	mu        sync.Mutex
+	cancelled map[uint64]struct{}
 }
 
+const (
+	spinYields   = 100
+	spinMaxSleep = time.Millisecond
+)
+
 // NewSpinSemaphore public constructor of spinSemaphore.
 func NewSpinSemaphore(limit int) Semaphore {
-	sm := &spinSemaphore{}
-	sm.cap.Store(int64(limit))
+	checkLimit(limit)
+
+	sm := &spinSemaphore{cancelled: make(map[uint64]struct{})}
+	sm.limit.Store(int64(limit))
 	return sm
 }
 
-func (s *spinSemaphore) Acquire() {
-	// при сильной конкуренции будет горячий цикл
-	for {
-		curr := s.cap.Load()
+func (s *spinSemaphore) Acquire(ctx context.Context, n int) error {
+	checkWeight(n)
+
+	ticket := s.next.Add(1) - 1
 
-		// спим
-		if curr == 0 {
-			runtime.Gosched()
-			continue
+	var b spinBackoff
+	for s.serving.Load() != ticket {
+		if ctx.Err() != nil {
+			s.cancel(ticket)
+			return ctx.Err()
 		}
+		b.wait()
+	}
 
-		// spin lock
-		for curr > 0 {
-			if s.cap.CompareAndSwap(curr, curr-1) {
-				// удалось захватить
-				return
-			}
-			curr = s.cap.Load()
+	// наша очередь - ждем места
+	defer s.advance()
+
+	for {
+		if s.take(n) {
+			return nil
 		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		b.wait()
 	}
 }
 
-func (s *spinSemaphore) Release() {
-	for {
-		curr := s.cap.Load()
+func (s *spinSemaphore) Release(n int) {
+	checkWeight(n)
 
-		// spin lock
-		// при сильной конкуренции будет много неудачных CAS и повторов.
-		if s.cap.CompareAndSwap(curr, curr+1) {
-			// удалось захватить
+	// проверка до записи, как в fifo.release: после recover семафор должен остаться в прежнем состоянии
+	for {
+		used := s.used.Load()
+		if used-int64(n) < 0 {
+			panic("semaphore: released more than held")
+		}
+		if s.used.CompareAndSwap(used, used-int64(n)) {
 			return
 		}
 	}
 }
 
-func (s *spinSemaphore) TryAcquire() bool {
-	curr := s.cap.Load()
+func (s *spinSemaphore) TryAcquire(n int) bool {
+	checkWeight(n)
 
-	if curr == 0 {
+	// занять очередь можно, только если она пуста: next == serving
+	ticket := s.serving.Load()
+	if !s.next.CompareAndSwap(ticket, ticket+1) {
 		return false
 	}
+	defer s.advance()
+
+	return s.take(n)
+}
+
+func (s *spinSemaphore) Resize(limit int) {
+	checkLimit(limit)
+	s.limit.Store(int64(limit))
+}
 
-	// Между Load и CompareAndSwap другой поток может забрать последний ресурс, и TryAcquire вернёт false.
-	// Что в прочем является стандартными поведением.
-	if s.cap.CompareAndSwap(curr, curr-1) {
-		return true
+// take занимает n слотов, если они есть. Вызывается только владельцем очереди,
+// но used конкурентно уменьшает Release, поэтому CAS.
+func (s *spinSemaphore) take(n int) bool {
+	for {
+		used := s.used.Load()
+		if used+int64(n) > s.limit.Load() {
+			return false
+		}
+		if s.used.CompareAndSwap(used, used+int64(n)) {
+			return true
+		}
+	}
+}
+
+// advance передает очередь следующему билету, пропуская отмененные. Вызывает владелец очереди.
+func (s *spinSemaphore) advance() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.advanceLocked()
+}
+
+func (s *spinSemaphore) advanceLocked() {
+	next := s.serving.Load() + 1
+	for {
+		if _, ok := s.cancelled[next]; !ok {
+			break
+		}
+		delete(s.cancelled, next)
+		next++
 	}
-	return false
+	s.serving.Store(next)
+}
+
+// cancel отказывается от билета, очередь которого еще не пришла (или пришла только что).
+func (s *spinSemaphore) cancel(ticket uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// serving меняется только под mu, поэтому проверка надежна: либо очередь уже наша и передаем ее сами,
+	// либо ее передаст тот, кто дойдет до нашего билета
+	if s.serving.Load() == ticket {
+		s.advanceLocked()
+		return
+	}
+	s.cancelled[ticket] = struct{}{}
+}
+
+// spinBackoff - пауза между проверками: сначала Gosched, потом растущий sleep.
+type spinBackoff struct {
+	attempt int
+	sleep   time.Duration
+}
+
+func (b *spinBackoff) wait() {
+	b.attempt++
+	if b.attempt <= spinYields {
+		runtime.Gosched()
+		return
+	}
+
+	b.sleep = min(max(2*b.sleep, time.Microsecond), spinMaxSleep)
+	time.Sleep(b.sleep)
 }
@@ -2,6 +2,7 @@ package throttler
 
 import (
 	"context"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
 	"slices"
 	"testing"
 	"time"
@@ -37,6 +38,26 @@ func TestTickerThrottler(t *testing.T) {
 
 		checkCancellation(t, inputCh, outputCh, 50, cancel)
 	})
+
+	t.Run("fake_clock", func(t *testing.T) {
+		c := clock.NewFake()
+
+		inputCh := make(chan int)
+		outputCh := TickerThrottler(t.Context(), inputCh, 50*time.Millisecond, clock.With(c))
+
+		inputCh <- 1
+		mustReceive(t, outputCh, 1)
+
+		// тик уже случился - значение уходит сразу
+		c.Advance(50 * time.Millisecond)
+		inputCh <- 2
+		mustReceive(t, outputCh, 2)
+
+		// тика не было - значение придерживается
+		inputCh <- 3
+		close(inputCh)
+		mustBeClosed(t, outputCh)
+	})
 }
 
 func TestTimeThrottler(t *testing.T) {
@@ -69,6 +90,62 @@ func TestTimeThrottler(t *testing.T) {
 
 		checkCancellation(t, inputCh, outputCh, 50, cancel)
 	})
+
+	t.Run("fake_clock_drops", func(t *testing.T) {
+		c := clock.NewFake()
+
+		inputCh := make(chan int)
+		outputCh := TimeThrottler(t.Context(), inputCh, 50*time.Millisecond, clock.With(c))
+
+		inputCh <- 1
+		mustReceive(t, outputCh, 1)
+
+		// время не двигается - все остальные отбрасываются
+		inputCh <- 2
+		inputCh <- 3
+		close(inputCh)
+		mustBeClosed(t, outputCh)
+	})
+
+	t.Run("fake_clock_passes", func(t *testing.T) {
+		c := clock.NewFake()
+
+		inputCh := make(chan int)
+		outputCh := TimeThrottler(t.Context(), inputCh, 50*time.Millisecond, clock.With(c))
+
+		inputCh <- 1
+		mustReceive(t, outputCh, 1)
+
+		// Время отправки фиксируется уже после того как мы прочитали значение.
+		// Раз следующее значение прочитано, то предыдущая итерация цикла завершена.
+		inputCh <- 2
+
+		c.Advance(50 * time.Millisecond)
+		inputCh <- 3
+		mustReceive(t, outputCh, 3)
+	})
+}
+
+func mustReceive(t *testing.T, outputCh <-chan int, want int) {
+	select {
+	case val := <-outputCh:
+		if val != want {
+			t.Fatalf("got %v, want %v", val, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting %v", want)
+	}
+}
+
+func mustBeClosed(t *testing.T, outputCh <-chan int) {
+	select {
+	case val, ok := <-outputCh:
+		if ok {
+			t.Fatalf("got value %v, want closed", val)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting close")
+	}
 }
 
 func checkSingleValue(t *testing.T, inputCh chan int, outputCh <-chan int, period int) {
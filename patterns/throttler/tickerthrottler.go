@@ -2,12 +2,14 @@ package throttler
 
 import (
 	"context"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
 	"time"
 )
 
 // TickerThrottler - работает на основе ticker.
 // @idiomatic defer ordering
-func TickerThrottler[T any](ctx context.Context, inputCh <-chan T, limit time.Duration) <-chan T {
+func TickerThrottler[T any](ctx context.Context, inputCh <-chan T, limit time.Duration, opts ...clock.Option) <-chan T {
+	clk := clock.FromOptions(opts)
 	outputCh := make(chan T)
 
 	// Выдает в канал ticker.C данные раз в limit времени.
@@ -18,7 +20,7 @@ func TickerThrottler[T any](ctx context.Context, inputCh <-chan T, limit time.Du
 	// Часто говорят что он может накапливать тики, но это не совсем так. Внутри у него буферизированный канал размером 1,
 	// каждый новый тик отбрасывается если буфер уже заполнен.
 	// Дело в том что если goroutine долго не читает из ticker.C, и потом начинает читать, она может получить тик сразу — потому что буфер уже содержит одно «накопленное» значение.
-	ticker := time.NewTicker(limit)
+	ticker := clk.NewTicker(limit)
 
 	// Здесь используем именно указатель, так как нам отправленные значения "забывать", чтобы не отправить старое значение
 	// при втором срабатывании ticker.
@@ -77,7 +79,7 @@ func TickerThrottler[T any](ctx context.Context, inputCh <-chan T, limit time.Du
 				select {
 				case <-ctx.Done():
 					return
-				case <-ticker.C:
+				case <-ticker.C():
 					// Если значение есть - то отправляем
 					// Запись осуществляем также с проверкой контекста.
 					select {
@@ -2,6 +2,7 @@ package throttler
 
 import (
 	"context"
+	"github.com/gallyamow/golang-just-for-fun/patterns/clock"
 	"time"
 )
 
@@ -9,7 +10,8 @@ import (
 // @idiomatic simplest channel throttler
 // @idiomatic if statement with a short variable declaration
 // @idiomatic waiting periods (time.After)
-func TimeThrottler[T any](ctx context.Context, inputCh <-chan T, limit time.Duration) <-chan T {
+func TimeThrottler[T any](ctx context.Context, inputCh <-chan T, limit time.Duration, opts ...clock.Option) <-chan T {
+	clk := clock.FromOptions(opts)
 	outputCh := make(chan T)
 
 	// благодаря zero-time выполняется требование Leading Edge
@@ -34,13 +36,13 @@ func TimeThrottler[T any](ctx context.Context, inputCh <-chan T, limit time.Dura
 					*lastVal = val
 				}
 
-				if since := time.Since(lastSent); since >= limit {
+				if since := clk.Since(lastSent); since >= limit {
 					select {
 					// Запись так же с реакцией на контекст
 					case <-ctx.Done():
 						return
 					case outputCh <- *lastVal:
-						lastSent = time.Now()
+						lastSent = clk.Now()
 						lastVal = nil
 					}
 				}
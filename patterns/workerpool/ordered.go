@@ -0,0 +1,100 @@
+package workerpool
+
+import (
+	"context"
+)
+
+// OrderedMap - как WorkerPool, но результаты выдаются в порядке поступления задач, а не в порядке завершения.
+//
+// Идея:
+// Для каждой задачи заводим канал-"обещание" на 1 результат и кладем его в очередь order в порядке поступления.
+// Workers выполняют задачи параллельно и пишут результат в обещание, а выдающая goroutine читает обещания
+// строго по очереди. Медленная задача задерживает выдачу следующих, но не их выполнение.
+//
+// Буфер переупорядочивания ограничен window: задач, которые уже взяты, но еще не выданы, не больше window.
+// Если одна задача зависла, то через window задач прием новых остановится, и память не растет.
+// Поэтому window меньше poolSize ограничивает и параллельность.
+// @idiomatic: channel of channels (promise queue)
+func OrderedMap[T any, R any](ctx context.Context, inputCh <-chan T, handler func(job T, workerId int) Result[T, R], poolSize int, window int) <-chan Result[T, R] {
+	if poolSize <= 0 || window <= 0 {
+		panic("pool size and window must be greater than 0")
+	}
+
+	type job struct {
+		val     T
+		promise chan Result[T, R]
+	}
+
+	jobsCh := make(chan job)
+	// еще одно обещание держит emitter, пока ждет его результат
+	orderCh := make(chan chan Result[T, R], window-1)
+	outputCh := make(chan Result[T, R])
+
+	// dispatcher: сохраняет порядок и ограничивает количество задач в работе
+	go func() {
+		defer close(jobsCh)
+		defer close(orderCh)
+
+		for {
+			var val T
+			var ok bool
+
+			select {
+			case <-ctx.Done():
+				return
+			case val, ok = <-inputCh:
+				if !ok {
+					return
+				}
+			}
+
+			// буфер на 1, чтобы worker никогда не блокировался на записи результата
+			promise := make(chan Result[T, R], 1)
+
+			// блокируется, когда window заполнено
+			select {
+			case <-ctx.Done():
+				return
+			case orderCh <- promise:
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case jobsCh <- job{val, promise}:
+			}
+		}
+	}()
+
+	// workers завершаются, когда dispatcher закрывает jobsCh
+	for i := range poolSize {
+		go func(workerId int) {
+			for j := range jobsCh {
+				j.promise <- handler(j.val, workerId)
+			}
+		}(i)
+	}
+
+	// emitter: выдает результаты по порядку
+	go func() {
+		defer close(outputCh)
+
+		for promise := range orderCh {
+			var res Result[T, R]
+
+			select {
+			case <-ctx.Done():
+				return
+			case res = <-promise:
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case outputCh <- res:
+			}
+		}
+	}()
+
+	return outputCh
+}
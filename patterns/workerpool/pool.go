@@ -0,0 +1,638 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+var (
+	ErrPoolClosed = errors.New("pool is closed")
+	ErrQueueFull  = errors.New("pool queue is full")
+)
+
+// OverflowPolicy - что делать с задачей, если очередь заполнена.
+type OverflowPolicy int
+
+const (
+	// Block - ждать места в очереди (или отмены ctx).
+	Block OverflowPolicy = iota
+	// Reject - сразу вернуть ErrQueueFull.
+	Reject
+	// CallerRuns - выполнить задачу в goroutine вызывающего (естественный backpressure: пока он занят, он не шлет новые).
+	CallerRuns
+)
+
+// Task - задача для Pool.
+type Task[R any] func(ctx context.Context) (R, error)
+
+// PanicError - паника внутри задачи, превращенная в ошибку, чтобы не уронить worker (и весь процесс).
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", e.Value)
+}
+
+// Pool - долгоживущий пул workers, в отличие от WorkerPool задачи можно отправлять из разных мест
+// и ждать результат конкретной задачи через Future.
+//
+// Требования:
+//   - очередь ограничена, при ее заполнении действует OverflowPolicy
+//   - Shutdown перестает принимать задачи и дожидается выполнения уже принятых
+//   - ShutdownNow отменяет контекст выполняющихся задач, а задачи из очереди завершает без выполнения
+//   - паника в задаче возвращается как *PanicError
+//   - с WithAutoscaling количество workers меняется от workers до Max (см. AutoscaleConfig)
+//   - с WithPriorityScheduling и WithKeyedScheduling очередь учитывает приоритет и ключ задачи (см. scheduler)
+type Pool[R any] struct {
+	queue       chan queuedTask
+	sched       *scheduler // nil - без приоритетов и ключей, задачи идут прямо в queue
+	prioritized bool
+	keyed       bool
+	policy      OverflowPolicy
+	onError     func(error)
+	scale       AutoscaleConfig
+
+	// statsMu защищает счетчики workers и метрики
+	statsMu     sync.Mutex
+	minWorkers  int
+	workers     int
+	busy        int
+	lastDequeue time.Time
+	stats       PoolStats
+
+	ctx    context.Context // отменяется в ShutdownNow
+	cancel context.CancelFunc
+
+	// mu защищает отправку в queue от ее закрытия: Submit держит RLock, Shutdown берет Lock.
+	mu       sync.RWMutex
+	closed   bool
+	quit     chan struct{} // закрывается в начале shutdown, чтобы разбудить заблокированных в Submit
+	quitOnce sync.Once
+
+	wg sync.WaitGroup
+}
+
+// NewPool создает пул из workers goroutines с очередью на queueSize задач.
+func NewPool[R any](workers int, queueSize int, opts ...PoolOptionFunc) *Pool[R] {
+	if workers <= 0 {
+		panic("workers must be greater than 0")
+	}
+	if queueSize < 0 {
+		panic("queue size must be greater or equal than 0")
+	}
+
+	o := poolOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if o.scale.Max > 0 && o.scale.Max < workers {
+		panic("autoscaling max must be greater or equal than workers")
+	}
+
+	p := &Pool[R]{
+		queue:       make(chan queuedTask, queueSize),
+		prioritized: o.priority,
+		keyed:       o.keyed,
+		policy:      o.policy,
+		onError:     o.onError,
+		scale:       o.scale,
+		minWorkers:  workers,
+		lastDequeue: time.Now(),
+		ctx:         ctx,
+		cancel:      cancel,
+		quit:        make(chan struct{}),
+	}
+
+	if o.priority || o.keyed {
+		if queueSize == 0 {
+			panic("priority and keyed scheduling require queue size greater than 0")
+		}
+
+		// очередь - в scheduler, а канал только передает задачу свободному worker
+		p.queue = make(chan queuedTask)
+		p.sched = newScheduler(queueSize, o.aging)
+		go p.sched.dispatch(p.queue)
+	}
+
+	for range workers {
+		p.spawn()
+	}
+
+	if p.scale.Max > 0 {
+		go p.supervise()
+	}
+
+	return p
+}
+
+// queuedTask - задача и время постановки в очередь (для queue latency и aging).
+type queuedTask struct {
+	run      func()
+	at       time.Time
+	priority int
+	key      string
+}
+
+// AutoscaleConfig - параметры автомасштабирования.
+//
+// Фиксированный размер пула - всегда компромисс: в простое лишние goroutines, а при всплеске их не хватает.
+// Вместо количества задач в очереди (которое ничего не говорит о том, сколько их ждать) ориентируемся
+// на queue latency - сколько задача провела в очереди. Это то, что чувствует вызывающий.
+type AutoscaleConfig struct {
+	Max          int           // максимальное количество workers, минимальное - workers из NewPool
+	QueueLatency time.Duration // если задача ждала в очереди дольше, добавляется worker
+	IdleTimeout  time.Duration // worker без задач дольше этого времени завершается (пока workers > минимума)
+}
+
+// PoolStats - метрики пула.
+type PoolStats struct {
+	Workers      int
+	Busy         int // workers, занятые задачами, без задач CallerRuns
+	QueueDepth   int
+	Completed    uint64        // включая задачи, выполненные вызывающим (CallerRuns)
+	QueueLatency time.Duration // EWMA времени ожидания в очереди
+	TaskLatency  time.Duration // EWMA времени выполнения
+}
+
+// ewmaAlpha - вес нового наблюдения: ~ последние 10 задач.
+const ewmaAlpha = 0.1
+
+// Stats возвращает текущие метрики.
+func (p *Pool[R]) Stats() PoolStats {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+
+	stats := p.stats
+	stats.Workers = p.workers
+	stats.Busy = p.busy
+	stats.QueueDepth = p.queueDepth()
+
+	return stats
+}
+
+// Submit ставит задачу в очередь и возвращает Future ее результата.
+// Ошибки постановки (ErrPoolClosed, ErrQueueFull, отмена ctx) тоже возвращаются через Future.
+// ctx задачи отменяется при отмене ctx и при ShutdownNow.
+func (p *Pool[R]) Submit(ctx context.Context, task Task[R], opts ...TaskOptionFunc) *Future[R] {
+	f := newFuture[R]()
+
+	run := func() {
+		taskCtx, cancel := p.taskContext(ctx)
+		defer cancel()
+
+		if taskCtx.Err() != nil {
+			var zero R
+			f.complete(zero, taskCtx.Err())
+			return
+		}
+
+		f.complete(protect(taskCtx, task))
+	}
+
+	if err := p.enqueue(ctx, run, opts); err != nil {
+		var zero R
+		f.complete(zero, err)
+	}
+
+	return f
+}
+
+// Go ставит в очередь задачу без результата. Паника передается в обработчик WithErrorHandler.
+func (p *Pool[R]) Go(fn func(ctx context.Context), opts ...TaskOptionFunc) error {
+	run := func() {
+		ctx, cancel := p.taskContext(context.Background())
+		defer cancel()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		_, err := protect(ctx, func(ctx context.Context) (struct{}, error) {
+			fn(ctx)
+			return struct{}{}, nil
+		})
+		if err != nil && p.onError != nil {
+			p.onError(err)
+		}
+	}
+
+	return p.enqueue(context.Background(), run, opts)
+}
+
+// Shutdown перестает принимать задачи и ждет, пока workers выполнят все принятые.
+// Если ctx отменен раньше, возвращает ctx.Err(), задачи при этом продолжают выполняться.
+func (p *Pool[R]) Shutdown(ctx context.Context) error {
+	p.close()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+		return nil
+	}
+}
+
+// ShutdownNow перестает принимать задачи и отменяет контекст выполняющихся и ожидающих в очереди задач.
+// Не ждет завершения: задача, которая игнорирует ctx, продолжит выполняться.
+func (p *Pool[R]) ShutdownNow() {
+	p.cancel()
+	p.close()
+}
+
+func (p *Pool[R]) spawn() {
+	p.statsMu.Lock()
+	p.workers++
+	p.statsMu.Unlock()
+
+	p.wg.Add(1)
+	go p.worker()
+}
+
+func (p *Pool[R]) worker() {
+	defer p.wg.Done()
+
+	// без автомасштабирования idle = nil, такой case никогда не срабатывает
+	var idle <-chan time.Time
+	var timer *time.Timer
+	if p.scale.Max > 0 && p.scale.IdleTimeout > 0 {
+		timer = time.NewTimer(p.scale.IdleTimeout)
+		defer timer.Stop()
+		idle = timer.C
+	}
+
+	for {
+		select {
+		case task, ok := <-p.queue:
+			// очередь закрывается в close, после чего workers дочитывают ее и выходят
+			if !ok {
+				p.exit()
+				return
+			}
+
+			p.run(task)
+		case <-idle:
+			if p.retire() {
+				return
+			}
+		}
+
+		if timer != nil {
+			timer.Reset(p.scale.IdleTimeout)
+		}
+	}
+}
+
+func (p *Pool[R]) run(task queuedTask) {
+	start := time.Now()
+	waited := start.Sub(task.at)
+
+	p.statsMu.Lock()
+	p.busy++
+	p.lastDequeue = start
+	p.stats.QueueLatency = ewma(p.stats.QueueLatency, waited)
+	p.statsMu.Unlock()
+
+	if p.scale.Max > 0 && waited > p.scale.QueueLatency && p.queueDepth() > 0 {
+		p.grow()
+	}
+
+	p.execute(task)
+
+	p.statsMu.Lock()
+	p.busy--
+	p.statsMu.Unlock()
+}
+
+// execute выполняет задачу и учитывает ее в Completed и TaskLatency.
+// Вызывается и из worker, и для CallerRuns - задача, выполненная вызывающим, тоже попадает в метрики.
+func (p *Pool[R]) execute(task queuedTask) {
+	start := time.Now()
+
+	task.run()
+
+	if task.key != "" {
+		p.sched.done(task.key)
+	}
+
+	p.statsMu.Lock()
+	p.stats.Completed++
+	p.stats.TaskLatency = ewma(p.stats.TaskLatency, time.Since(start))
+	p.statsMu.Unlock()
+}
+
+// grow добавляет worker, если не достигнут максимум.
+func (p *Pool[R]) grow() {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+
+	if p.workers >= p.scale.Max {
+		return
+	}
+
+	p.workers++
+	p.wg.Add(1)
+	go p.worker()
+}
+
+// retire завершает простаивающий worker, если их больше минимума.
+func (p *Pool[R]) retire() bool {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+
+	if p.workers <= p.minWorkers {
+		return false
+	}
+
+	p.workers--
+	return true
+}
+
+func (p *Pool[R]) exit() {
+	p.statsMu.Lock()
+	p.workers--
+	p.statsMu.Unlock()
+}
+
+// supervise раз в QueueLatency проверяет, не застряла ли очередь.
+// Одной проверки в run недостаточно: если все workers заняты долгими задачами, run долго не вызывается.
+func (p *Pool[R]) supervise() {
+	ticker := time.NewTicker(p.scale.QueueLatency)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.quit:
+			return
+		case <-ticker.C:
+			// под RLock очередь еще не закрыта, поэтому новый worker успеет стартовать до Shutdown
+			p.mu.RLock()
+			if !p.closed && p.starving() {
+				p.grow()
+			}
+			p.mu.RUnlock()
+		}
+	}
+}
+
+// starving - все workers заняты, а из очереди давно ничего не забирали.
+// Задачи в голове очереди ждут как минимум столько же, а увидеть это в run не получится, пока кто-то не освободится.
+func (p *Pool[R]) starving() bool {
+	if p.scale.Max == 0 || p.queueDepth() == 0 {
+		return false
+	}
+
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+
+	return p.busy == p.workers && time.Since(p.lastDequeue) > p.scale.QueueLatency
+}
+
+func ewma(avg time.Duration, val time.Duration) time.Duration {
+	if avg == 0 {
+		return val
+	}
+	return time.Duration(ewmaAlpha*float64(val) + (1-ewmaAlpha)*float64(avg))
+}
+
+func (p *Pool[R]) queueDepth() int {
+	if p.sched != nil {
+		return p.sched.len()
+	}
+	return len(p.queue)
+}
+
+func (p *Pool[R]) enqueue(ctx context.Context, run func(), opts []TaskOptionFunc) error {
+	var o taskOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	task := queuedTask{run: run}
+	if p.prioritized {
+		task.priority = o.priority
+	}
+	if p.keyed {
+		task.key = o.key
+	}
+
+	inline, err := p.tryEnqueue(ctx, task)
+	if inline {
+		// вне mutex, чтобы долгая задача не задерживала Shutdown.
+		// Busy и QueueLatency не меняются: задача не была в очереди и не занимала worker.
+		p.execute(task)
+	}
+	return err
+}
+
+// tryEnqueue ставит задачу в очередь, inline = true - очередь заполнена и задачу надо выполнить самому (CallerRuns).
+func (p *Pool[R]) tryEnqueue(ctx context.Context, task queuedTask) (inline bool, err error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return false, ErrPoolClosed
+	}
+
+	task.at = time.Now()
+
+	if p.sched != nil {
+		return p.trySchedule(ctx, task)
+	}
+
+	switch p.policy {
+	case Reject:
+		select {
+		case p.queue <- task:
+			return false, nil
+		default:
+			return false, ErrQueueFull
+		}
+	case CallerRuns:
+		select {
+		case p.queue <- task:
+			return false, nil
+		default:
+			return true, nil
+		}
+	default:
+		select {
+		case p.queue <- task:
+			return false, nil
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-p.quit:
+			return false, ErrPoolClosed
+		}
+	}
+}
+
+// trySchedule - tryEnqueue для режима с scheduler.
+// Задачу с ключом нельзя выполнить в обход очереди ключа, поэтому для нее CallerRuns работает как Block.
+func (p *Pool[R]) trySchedule(ctx context.Context, task queuedTask) (inline bool, err error) {
+	switch {
+	case p.policy == Reject:
+		if ok, _ := p.sched.push(task); !ok {
+			return false, ErrQueueFull
+		}
+		return false, nil
+	case p.policy == CallerRuns && task.key == "":
+		ok, _ := p.sched.push(task)
+		return !ok, nil
+	default:
+		return false, p.sched.pushWait(ctx, p.quit, task)
+	}
+}
+
+func (p *Pool[R]) close() {
+	// сначала будим тех, кто ждет места в очереди, иначе Lock будет ждать их вечно
+	p.quitOnce.Do(func() {
+		close(p.quit)
+	})
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.closed {
+		p.closed = true
+
+		// в режиме scheduler канал закроет dispatcher, когда раздаст все задачи
+		if p.sched != nil {
+			p.sched.close()
+		} else {
+			close(p.queue)
+		}
+	}
+}
+
+// taskContext - ctx задачи, который дополнительно отменяется при ShutdownNow.
+func (p *Pool[R]) taskContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	// AfterFunc для уже отмененного p.ctx вызывает cancel асинхронно, а задача из очереди должна увидеть отмену сразу.
+	if p.ctx.Err() != nil {
+		cancel()
+	}
+	stop := context.AfterFunc(p.ctx, cancel)
+
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// protect выполняет задачу, превращая панику в *PanicError.
+func protect[R any](ctx context.Context, task Task[R]) (val R, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+
+	return task(ctx)
+}
+
+// Future - результат задачи, который будет готов позже.
+type Future[R any] struct {
+	done chan struct{}
+	val  R
+	err  error
+}
+
+func newFuture[R any]() *Future[R] {
+	return &Future[R]{done: make(chan struct{})}
+}
+
+// Done закрывается, когда результат готов.
+func (f *Future[R]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Get ждет результат задачи. Отмена ctx прекращает только ожидание, но не саму задачу.
+func (f *Future[R]) Get(ctx context.Context) (R, error) {
+	select {
+	case <-ctx.Done():
+		var zero R
+		return zero, ctx.Err()
+	case <-f.done:
+		return f.val, f.err
+	}
+}
+
+// complete вызывается ровно один раз: запись val/err происходит до close, поэтому чтение после <-done безопасно.
+func (f *Future[R]) complete(val R, err error) {
+	f.val, f.err = val, err
+	close(f.done)
+}
+
+type poolOptions struct {
+	policy   OverflowPolicy
+	onError  func(error)
+	scale    AutoscaleConfig
+	priority bool
+	aging    time.Duration
+	keyed    bool
+}
+
+type PoolOptionFunc func(*poolOptions)
+
+func WithOverflowPolicy(val OverflowPolicy) PoolOptionFunc {
+	return func(o *poolOptions) {
+		o.policy = val
+	}
+}
+
+// WithErrorHandler задает обработчик ошибок задач, у которых нет Future (см. Go).
+func WithErrorHandler(val func(error)) PoolOptionFunc {
+	return func(o *poolOptions) {
+		o.onError = val
+	}
+}
+
+// WithAutoscaling включает автомасштабирование, workers из NewPool становится минимумом.
+func WithAutoscaling(val AutoscaleConfig) PoolOptionFunc {
+	if val.Max <= 0 {
+		panic("autoscaling max must be greater than 0")
+	}
+	if val.QueueLatency <= 0 {
+		panic("autoscaling queue latency must be greater than 0")
+	}
+
+	return func(o *poolOptions) {
+		o.scale = val
+	}
+}
+
+// WithPriorityScheduling включает очередь с приоритетами (см. WithTaskPriority).
+// aging - за каждый такой интервал ожидания приоритет задачи растет на 1, 0 - без aging.
+func WithPriorityScheduling(aging time.Duration) PoolOptionFunc {
+	if aging < 0 {
+		panic("aging must be greater or equal than 0, pass 0 if you want to disable aging")
+	}
+
+	return func(o *poolOptions) {
+		o.priority = true
+		o.aging = aging
+	}
+}
+
+// WithKeyedScheduling включает последовательное выполнение задач с одним ключом (см. WithTaskKey).
+func WithKeyedScheduling() PoolOptionFunc {
+	return func(o *poolOptions) {
+		o.keyed = true
+	}
+}
@@ -0,0 +1,412 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	t.Run("submit_and_get", func(t *testing.T) {
+		p := NewPool[int](3, 10)
+		defer p.ShutdownNow()
+
+		var futures []*Future[int]
+		for i := range 20 {
+			futures = append(futures, p.Submit(t.Context(), func(ctx context.Context) (int, error) {
+				return i * i, nil
+			}))
+		}
+
+		for i, f := range futures {
+			val, err := f.Get(t.Context())
+			if err != nil || val != i*i {
+				t.Fatalf("got %v, %v, want %v", val, err, i*i)
+			}
+		}
+	})
+
+	t.Run("panic_becomes_error", func(t *testing.T) {
+		p := NewPool[int](1, 1)
+		defer p.ShutdownNow()
+
+		_, err := p.Submit(t.Context(), func(ctx context.Context) (int, error) {
+			panic("boom")
+		}).Get(t.Context())
+
+		var panicErr *PanicError
+		if !errors.As(err, &panicErr) || panicErr.Value != "boom" {
+			t.Fatalf("got %v, want *PanicError", err)
+		}
+
+		// worker жив
+		val, err := p.Submit(t.Context(), func(ctx context.Context) (int, error) {
+			return 1, nil
+		}).Get(t.Context())
+		if err != nil || val != 1 {
+			t.Fatalf("got %v, %v, want 1, nil", val, err)
+		}
+	})
+
+	t.Run("go_panic_goes_to_handler", func(t *testing.T) {
+		errs := make(chan error, 1)
+		p := NewPool[struct{}](1, 1, WithErrorHandler(func(err error) {
+			errs <- err
+		}))
+		defer p.ShutdownNow()
+
+		if err := p.Go(func(ctx context.Context) { panic("boom") }); err != nil {
+			t.Fatalf("got error %v", err)
+		}
+
+		var panicErr *PanicError
+		if err := <-errs; !errors.As(err, &panicErr) {
+			t.Fatalf("got %v, want *PanicError", err)
+		}
+	})
+
+	t.Run("overflow_policies", func(t *testing.T) {
+		release := make(chan struct{})
+		blocking := func(ctx context.Context) (int, error) {
+			<-release
+			return 0, nil
+		}
+
+		// worker занят, очередь на 1 задачу заполнена
+		fill := func(p *Pool[int]) {
+			started := make(chan struct{})
+			p.Submit(t.Context(), func(ctx context.Context) (int, error) {
+				close(started)
+				return blocking(ctx)
+			})
+			<-started
+			p.Submit(t.Context(), blocking)
+		}
+
+		reject := NewPool[int](1, 1, WithOverflowPolicy(Reject))
+		fill(reject)
+		if _, err := reject.Submit(t.Context(), blocking).Get(t.Context()); !errors.Is(err, ErrQueueFull) {
+			t.Errorf("reject: got %v, want ErrQueueFull", err)
+		}
+
+		callerRuns := NewPool[int](1, 1, WithOverflowPolicy(CallerRuns))
+		fill(callerRuns)
+		f := callerRuns.Submit(t.Context(), func(ctx context.Context) (int, error) {
+			return 7, nil
+		})
+		// выполнена синхронно, результат готов сразу
+		select {
+		case <-f.Done():
+		default:
+			t.Errorf("caller runs: task was not run in caller")
+		}
+		// worker все еще занят, выполненная задача - та, что выполнил вызывающий
+		if st := callerRuns.Stats(); st.Completed != 1 || st.Busy != 1 {
+			t.Errorf("caller runs: got completed=%v busy=%v, want completed=1 busy=1", st.Completed, st.Busy)
+		}
+
+		block := NewPool[int](1, 1)
+		fill(block)
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+		defer cancel()
+		if _, err := block.Submit(ctx, blocking).Get(t.Context()); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("block: got %v, want DeadlineExceeded", err)
+		}
+
+		close(release)
+		for _, p := range []*Pool[int]{reject, callerRuns, block} {
+			if err := p.Shutdown(t.Context()); err != nil {
+				t.Errorf("shutdown: %v", err)
+			}
+		}
+	})
+
+	t.Run("shutdown_drains_queue", func(t *testing.T) {
+		p := NewPool[int](2, 100)
+
+		var done atomic.Int32
+		for range 50 {
+			p.Submit(t.Context(), func(ctx context.Context) (int, error) {
+				time.Sleep(time.Millisecond)
+				done.Add(1)
+				return 0, nil
+			})
+		}
+
+		if err := p.Shutdown(t.Context()); err != nil {
+			t.Fatalf("got error %v", err)
+		}
+
+		if done.Load() != 50 {
+			t.Errorf("got %v done, want 50", done.Load())
+		}
+
+		if _, err := p.Submit(t.Context(), func(ctx context.Context) (int, error) {
+			return 0, nil
+		}).Get(t.Context()); !errors.Is(err, ErrPoolClosed) {
+			t.Errorf("got %v, want ErrPoolClosed", err)
+		}
+	})
+
+	t.Run("shutdown_now_cancels", func(t *testing.T) {
+		p := NewPool[int](1, 10)
+
+		started := make(chan struct{})
+		running := p.Submit(t.Context(), func(ctx context.Context) (int, error) {
+			close(started)
+			<-ctx.Done()
+			return 0, ctx.Err()
+		})
+		<-started
+
+		var executed atomic.Bool
+		queued := p.Submit(t.Context(), func(ctx context.Context) (int, error) {
+			executed.Store(true)
+			return 0, nil
+		})
+
+		p.ShutdownNow()
+
+		for _, f := range []*Future[int]{running, queued} {
+			if _, err := f.Get(t.Context()); !errors.Is(err, context.Canceled) {
+				t.Errorf("got %v, want canceled", err)
+			}
+		}
+
+		if executed.Load() {
+			t.Errorf("queued task was executed")
+		}
+	})
+}
+
+func TestPoolAutoscaling(t *testing.T) {
+	p := NewPool[int](1, 100, WithAutoscaling(AutoscaleConfig{
+		Max:          4,
+		QueueLatency: 5 * time.Millisecond,
+		IdleTimeout:  20 * time.Millisecond,
+	}))
+	defer p.ShutdownNow()
+
+	release := make(chan struct{})
+	for range 20 {
+		p.Submit(t.Context(), func(ctx context.Context) (int, error) {
+			<-release
+			return 0, nil
+		})
+	}
+
+	// все заняты, очередь стоит - растем до максимума, но не больше
+	waitFor(t, func() bool { return p.Stats().Workers == 4 })
+	time.Sleep(20 * time.Millisecond)
+	if got := p.Stats().Workers; got != 4 {
+		t.Fatalf("got %v workers, want max 4", got)
+	}
+
+	close(release)
+
+	// после простоя возвращаемся к минимуму
+	waitFor(t, func() bool {
+		stats := p.Stats()
+		return stats.Workers == 1 && stats.QueueDepth == 0 && stats.Completed == 20
+	})
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition was not met")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// BenchmarkPoolBursty - всплески по 200 коротких задач с паузами между ними.
+// Автомасштабируемый пул в простое держит минимум workers, а на всплеске догоняет фиксированный большой пул.
+func BenchmarkPoolBursty(b *testing.B) {
+	cases := []struct {
+		name string
+		pool func() *Pool[int]
+	}{
+		{"fixed_2", func() *Pool[int] { return NewPool[int](2, 1000) }},
+		{"fixed_16", func() *Pool[int] { return NewPool[int](16, 1000) }},
+		{"autoscale_2_16", func() *Pool[int] {
+			return NewPool[int](2, 1000, WithAutoscaling(AutoscaleConfig{
+				Max:          16,
+				QueueLatency: time.Millisecond,
+				IdleTimeout:  10 * time.Millisecond,
+			}))
+		}},
+	}
+
+	task := func(ctx context.Context) (int, error) {
+		time.Sleep(100 * time.Microsecond)
+		return 0, nil
+	}
+
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			p := tc.pool()
+			defer p.ShutdownNow()
+
+			futures := make([]*Future[int], 200)
+			var maxWorkers int
+
+			for b.Loop() {
+				for i := range futures {
+					futures[i] = p.Submit(b.Context(), task)
+				}
+				for _, f := range futures {
+					_, _ = f.Get(b.Context())
+				}
+				maxWorkers = max(maxWorkers, p.Stats().Workers)
+
+				b.StopTimer()
+				time.Sleep(time.Millisecond)
+				b.StartTimer()
+			}
+
+			stats := p.Stats()
+			b.ReportMetric(float64(maxWorkers), "max-workers")
+			b.ReportMetric(float64(stats.QueueLatency.Microseconds()), "queue-µs")
+		})
+	}
+}
+
+func TestPoolScheduling(t *testing.T) {
+	// occupy занимает единственного worker, пока не закрыт release, чтобы задачи успели встать в очередь
+	occupy := func(p *Pool[int]) chan struct{} {
+		release := make(chan struct{})
+		started := make(chan struct{})
+		p.Submit(t.Context(), func(ctx context.Context) (int, error) {
+			close(started)
+			<-release
+			return 0, nil
+		})
+		<-started
+		return release
+	}
+
+	t.Run("priority", func(t *testing.T) {
+		p := NewPool[int](1, 100, WithPriorityScheduling(0))
+
+		release := occupy(p)
+
+		var mu sync.Mutex
+		var order []int
+		for _, priority := range []int{1, 5, 3, 5, 0} {
+			p.Submit(t.Context(), func(ctx context.Context) (int, error) {
+				mu.Lock()
+				order = append(order, priority)
+				mu.Unlock()
+				return 0, nil
+			}, WithTaskPriority(priority))
+		}
+
+		close(release)
+		if err := p.Shutdown(t.Context()); err != nil {
+			t.Fatalf("got error %v", err)
+		}
+
+		if !slices.Equal(order, []int{5, 5, 3, 1, 0}) {
+			t.Errorf("got %v, want by priority", order)
+		}
+	})
+
+	t.Run("aging", func(t *testing.T) {
+		p := NewPool[int](1, 100, WithPriorityScheduling(time.Millisecond))
+
+		release := occupy(p)
+
+		var mu sync.Mutex
+		var order []string
+		record := func(name string) Task[int] {
+			return func(ctx context.Context) (int, error) {
+				mu.Lock()
+				order = append(order, name)
+				mu.Unlock()
+				return 0, nil
+			}
+		}
+
+		p.Submit(t.Context(), record("old_low"), WithTaskPriority(0))
+		time.Sleep(20 * time.Millisecond)
+		// 20 интервалов aging старше, чем разница приоритетов
+		p.Submit(t.Context(), record("new_high"), WithTaskPriority(10))
+
+		close(release)
+		if err := p.Shutdown(t.Context()); err != nil {
+			t.Fatalf("got error %v", err)
+		}
+
+		if !slices.Equal(order, []string{"old_low", "new_high"}) {
+			t.Errorf("got %v, want aged task first", order)
+		}
+	})
+
+	t.Run("keyed", func(t *testing.T) {
+		p := NewPool[int](8, 1000, WithKeyedScheduling())
+
+		keys := []string{"a", "b", "c", "d"}
+
+		var mu sync.Mutex
+		running := make(map[string]int)
+		order := make(map[string][]int)
+		var maxParallel, parallel int
+
+		for i := range 200 {
+			key := keys[i%len(keys)]
+			p.Submit(t.Context(), func(ctx context.Context) (int, error) {
+				mu.Lock()
+				running[key]++
+				if running[key] > 1 {
+					t.Errorf("key %v runs concurrently", key)
+				}
+				parallel++
+				maxParallel = max(maxParallel, parallel)
+				order[key] = append(order[key], i)
+				mu.Unlock()
+
+				time.Sleep(100 * time.Microsecond)
+
+				mu.Lock()
+				running[key]--
+				parallel--
+				mu.Unlock()
+				return 0, nil
+			}, WithTaskKey(key))
+		}
+
+		if err := p.Shutdown(t.Context()); err != nil {
+			t.Fatalf("got error %v", err)
+		}
+
+		for _, key := range keys {
+			if len(order[key]) != 50 || !slices.IsSorted(order[key]) {
+				t.Errorf("key %v: got %v, want 50 tasks in submit order", key, order[key])
+			}
+		}
+
+		if maxParallel < 2 {
+			t.Errorf("got max %v parallel, want different keys in parallel", maxParallel)
+		}
+	})
+
+	t.Run("reject_when_full", func(t *testing.T) {
+		p := NewPool[int](1, 1, WithKeyedScheduling(), WithOverflowPolicy(Reject))
+		release := occupy(p)
+		defer close(release)
+		defer p.ShutdownNow()
+
+		noop := func(ctx context.Context) (int, error) { return 0, nil }
+		p.Submit(t.Context(), noop, WithTaskKey("a"))
+
+		if _, err := p.Submit(t.Context(), noop, WithTaskKey("a")).Get(t.Context()); !errors.Is(err, ErrQueueFull) {
+			t.Errorf("got %v, want ErrQueueFull", err)
+		}
+	})
+}
@@ -0,0 +1,238 @@
+package workerpool
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// scheduler - очередь Pool для режимов WithPriorityScheduling и WithKeyedScheduling (вместо простого канала).
+//
+// Приоритет:
+// Heap по приоритету, при равном - FIFO. Чтобы низкий приоритет не голодал при постоянном потоке высокого,
+// используется aging: за каждые aging ожидания приоритет задачи растет на 1.
+// Так как стареют все задачи одинаково, порядок двух задач со временем не меняется, и вместо пересчета
+// приоритетов достаточно статичного ключа: at - priority*aging (чем меньше, тем раньше).
+//
+// Ключи:
+// Задачи с одним ключом выполняются строго последовательно в порядке постановки, с разными - параллельно.
+// В heap лежит только первая задача каждого ключа, остальные ждут в списке ключа и попадают в heap,
+// когда предыдущая задача ключа завершится (done). Поэтому в heap только задачи, которые можно запускать.
+type scheduler struct {
+	mu      sync.Mutex
+	heap    taskHeap
+	keys    map[string][]*scheduled // ключ с выполняющейся или стоящей в heap задачей -> ожидающие за ней
+	pending int                     // сколько задач ждет в списках ключей
+	cap     int
+	aging   time.Duration
+	seq     uint64
+	closed  bool
+
+	wake  chan struct{} // буфер 1: сигнал dispatcher, что что-то изменилось
+	space chan struct{} // закрывается, когда освободилось место (для Block)
+}
+
+type scheduled struct {
+	task  queuedTask
+	score int64
+	seq   uint64
+	index int // позиция в heap, нужна для heap.Remove
+}
+
+func newScheduler(cap int, aging time.Duration) *scheduler {
+	return &scheduler{
+		keys:  make(map[string][]*scheduled),
+		cap:   cap,
+		aging: aging,
+		wake:  make(chan struct{}, 1),
+		space: make(chan struct{}),
+	}
+}
+
+// push добавляет задачу, если есть место. Иначе возвращает канал, который закроется, когда место появится.
+func (s *scheduler) push(task queuedTask) (bool, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.heap.Len()+s.pending >= s.cap {
+		return false, s.space
+	}
+
+	s.seq++
+	item := &scheduled{
+		task:  task,
+		score: -int64(task.priority), // без aging: строго по приоритету, при равном - по seq
+		seq:   s.seq,
+	}
+	if s.aging > 0 {
+		item.score = task.at.UnixNano() - int64(task.priority)*int64(s.aging)
+	}
+
+	if task.key != "" {
+		if waiting, ok := s.keys[task.key]; ok {
+			s.keys[task.key] = append(waiting, item)
+			s.pending++
+			return true, nil
+		}
+		s.keys[task.key] = nil
+	}
+
+	heap.Push(&s.heap, item)
+	s.notify()
+
+	return true, nil
+}
+
+// done вызывается после выполнения задачи с ключом: следующая задача ключа становится доступной.
+func (s *scheduler) done(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	waiting := s.keys[key]
+	if len(waiting) == 0 {
+		delete(s.keys, key)
+		// после закрытия dispatcher ждет, пока не останется ожидающих задач
+		s.notify()
+		return
+	}
+
+	next := waiting[0]
+	s.keys[key] = waiting[1:]
+	s.pending--
+
+	// score посчитан еще в push, поэтому время ожидания в списке ключа тоже идет в зачет aging
+	heap.Push(&s.heap, next)
+	s.notify()
+}
+
+func (s *scheduler) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	s.notify()
+}
+
+func (s *scheduler) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.heap.Len() + s.pending
+}
+
+// dispatch передает задачи из heap в канал workers, пока scheduler не закрыт и не пуст, затем закрывает out.
+// Задача удаляется из heap только после передачи worker: если пока ждали свободного worker пришла задача
+// важнее, то передаем ее.
+func (s *scheduler) dispatch(out chan<- queuedTask) {
+	defer close(out)
+
+	for {
+		s.mu.Lock()
+		if s.heap.Len() == 0 {
+			finished := s.closed && s.pending == 0
+			s.mu.Unlock()
+
+			if finished {
+				return
+			}
+
+			<-s.wake
+			continue
+		}
+		top := s.heap[0]
+		s.mu.Unlock()
+
+		select {
+		case out <- top.task:
+			s.mu.Lock()
+			heap.Remove(&s.heap, top.index)
+			close(s.space)
+			s.space = make(chan struct{})
+			s.mu.Unlock()
+		case <-s.wake:
+			// пересмотреть вершину heap
+		}
+	}
+}
+
+func (s *scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// pushWait - push с ожиданием места (политика Block).
+func (s *scheduler) pushWait(ctx context.Context, quit <-chan struct{}, task queuedTask) error {
+	for {
+		ok, space := s.push(task)
+		if ok {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-quit:
+			return ErrPoolClosed
+		case <-space:
+		}
+	}
+}
+
+// taskHeap - реализация heap.Interface.
+type taskHeap []*scheduled
+
+func (h taskHeap) Len() int {
+	return len(h)
+}
+
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].score != h[j].score {
+		return h[i].score < h[j].score
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h taskHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *taskHeap) Push(x any) {
+	item := x.(*scheduled)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *taskHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return item
+}
+
+type taskOptions struct {
+	priority int
+	key      string
+}
+
+// TaskOptionFunc - параметры отдельной задачи в Submit и Go.
+type TaskOptionFunc func(*taskOptions)
+
+// WithTaskPriority - чем больше, тем раньше выполнится задача. Учитывается только с WithPriorityScheduling.
+func WithTaskPriority(val int) TaskOptionFunc {
+	return func(o *taskOptions) {
+		o.priority = val
+	}
+}
+
+// WithTaskKey - задачи с одним ключом выполняются последовательно. Учитывается только с WithKeyedScheduling.
+func WithTaskKey(val string) TaskOptionFunc {
+	return func(o *taskOptions) {
+		o.key = val
+	}
+}
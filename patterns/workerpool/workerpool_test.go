@@ -136,3 +136,84 @@ func TestWorkerPool(t *testing.T) {
 		}
 	})
 }
+
+func TestOrderedMap(t *testing.T) {
+	t.Run("keeps_input_order", func(t *testing.T) {
+		inputCh := make(chan int)
+		go func() {
+			for i := range 100 {
+				inputCh <- i
+			}
+			close(inputCh)
+		}()
+
+		outputCh := OrderedMap[int, int](t.Context(), inputCh, func(job int, _ int) Result[int, int] {
+			time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+			return Result[int, int]{Job: job, Result: job * 2}
+		}, 8, 16)
+
+		var want int
+		for res := range outputCh {
+			if res.Job != want || res.Result != want*2 {
+				t.Fatalf("got %+v, want job %v", res, want)
+			}
+			want++
+		}
+
+		if want != 100 {
+			t.Errorf("got %v results, want 100", want)
+		}
+	})
+
+	t.Run("bounded_by_window", func(t *testing.T) {
+		window := 4
+		release := make(chan struct{})
+
+		inputCh := make(chan int)
+		go func() {
+			defer close(inputCh)
+			for i := range 100 {
+				select {
+				case inputCh <- i:
+				case <-t.Context().Done():
+					return
+				}
+			}
+		}()
+
+		var mu sync.Mutex
+		var started int
+
+		outputCh := OrderedMap[int, int](t.Context(), inputCh, func(job int, _ int) Result[int, int] {
+			mu.Lock()
+			started++
+			mu.Unlock()
+
+			// первая задача зависла, остальные быстрые
+			if job == 0 {
+				<-release
+			}
+			return Result[int, int]{Job: job}
+		}, 8, window)
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		got := started
+		mu.Unlock()
+
+		if got > window {
+			t.Fatalf("got %v started, want <= %v", got, window)
+		}
+
+		close(release)
+
+		var count int
+		for range outputCh {
+			count++
+		}
+		if count != 100 {
+			t.Errorf("got %v results, want 100", count)
+		}
+	})
+}